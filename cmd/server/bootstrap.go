@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+var errAdminPasswordRequired = errors.New("ADMIN_PASSWORD is required to create the ADMIN_EMAIL account")
+
+// bootstrapAdmin makes sure the account named by ADMIN_EMAIL exists and holds the admin role,
+// creating it with ADMIN_PASSWORD when it does not. Nothing happens when no email is configured.
+func bootstrapAdmin(ctx context.Context, userDB database.UserInterface, name, email, password string) error {
+	if email == "" {
+		return nil
+	}
+
+	email = entities.NormalizeEmail(email)
+	user, err := userDB.FindByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if password == "" {
+			return errAdminPasswordRequired
+		}
+		if name == "" {
+			name = "Admin"
+		}
+
+		user, err = entities.NewUser(name, email, password)
+		if err != nil {
+			return err
+		}
+		user.Role = entities.RoleAdmin
+		user.Verified = true
+
+		return userDB.Create(ctx, user)
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.Role == entities.RoleAdmin {
+		return nil
+	}
+
+	return userDB.UpdateRole(ctx, user.ID.String(), entities.RoleAdmin)
+}
@@ -1,19 +1,30 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/caiocp/go-api/configs"
 	_ "github.com/caiocp/go-api/docs"
 	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/audit"
 	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+	"github.com/caiocp/go-api/internal/infra/lockout"
+	"github.com/caiocp/go-api/internal/infra/mail"
+	"github.com/caiocp/go-api/internal/infra/purge"
+	"github.com/caiocp/go-api/internal/infra/revocation"
 	"github.com/caiocp/go-api/internal/infra/webserver/handlers"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+	"github.com/caiocp/go-api/pkg/password"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 // @title Go Expert API Example
@@ -29,48 +40,287 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
+
+// @securityDefinitions.apikey PersonalAPIKey
+// @in header
+// @name X-API-Key
 func main() {
 	configs, err := configs.LoadConfig(".")
 	if err != nil {
 		panic(err)
 	}
 
-	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	dbConfig := database.Config{
+		Driver:          configs.DBDriver,
+		Host:            configs.DBHost,
+		Port:            configs.DBPort,
+		User:            configs.DBUser,
+		Password:        configs.DBPassword,
+		Name:            configs.DBName,
+		MaxOpenConns:    configs.DBMaxOpenConns,
+		MaxIdleConns:    configs.DBMaxIdleConns,
+		ConnMaxLifetime: time.Second * time.Duration(configs.DBConnMaxLifetime),
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(dbConfig, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	db, err := database.NewConnection(dbConfig)
 	if err != nil {
 		panic(err)
 	}
 
-	db.AutoMigrate(&entities.Product{}, &entities.User{})
+	err = migrations.NewMigrator(db, migrations.All()).EnsureCurrent()
+	if err != nil {
+		panic(err)
+	}
+
+	dbQueryTimeout := time.Second * time.Duration(configs.DBQueryTimeout)
 
 	productDB := database.NewProduct(db)
+	productDB.Timeout = dbQueryTimeout
 	userDB := database.NewUser(db)
+	userDB.Timeout = dbQueryTimeout
+	refreshTokenDB := database.NewRefreshToken(db)
+	refreshTokenDB.Timeout = dbQueryTimeout
+	passwordResetTokenDB := database.NewPasswordResetToken(db)
+	passwordResetTokenDB.Timeout = dbQueryTimeout
+	verificationTokenDB := database.NewEmailVerificationToken(db)
+	verificationTokenDB.Timeout = dbQueryTimeout
+	recoveryCodeDB := database.NewRecoveryCode(db)
+	recoveryCodeDB.Timeout = dbQueryTimeout
+	challengeDB := database.NewTwoFactorChallenge(db)
+	challengeDB.Timeout = dbQueryTimeout
+	apiKeyDB := database.NewAPIKey(db)
+	apiKeyDB.Timeout = dbQueryTimeout
+	oauthClientDB := database.NewOAuthClient(db)
+	oauthClientDB.Timeout = dbQueryTimeout
+	authorizationCodeDB := database.NewAuthorizationCode(db)
+	authorizationCodeDB.Timeout = dbQueryTimeout
+	transactionManager := database.NewTransactionManager(db)
+	transactionManager.Timeout = dbQueryTimeout
+
+	var revocations revocation.Store = revocation.NewMemory()
+	if configs.RevocationStore != "memory" {
+		tokenRevocationDB := database.NewTokenRevocation(db)
+		tokenRevocationDB.Timeout = dbQueryTimeout
+		revocations = revocation.NewLayered(tokenRevocationDB)
+	}
+	revocation.StartGC(context.Background(), revocations, time.Second*time.Duration(configs.RevocationGC))
+	// PRODUCT_TRASH_RETENTION=0 keeps deleted products until they are removed with ?hard=true
+	purge.Start(context.Background(), productDB, time.Second*time.Duration(configs.ProductTrashRetention),
+		time.Second*time.Duration(configs.ProductPurgeInterval))
+
+	// MAIL_DRIVER=log writes messages to MAIL_FILE, or stdout when it is empty
+	var mailer mail.Mailer = mail.NewLog(os.Stdout)
+	if configs.MailDriver == "smtp" {
+		mailer = mail.NewSMTP(configs.SMTPHost, configs.SMTPPort, configs.SMTPUsername, configs.SMTPPassword, configs.MailFrom)
+	} else if configs.MailFile != "" {
+		mailFile, err := os.OpenFile(configs.MailFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			panic(err)
+		}
+		defer mailFile.Close()
+		mailer = mail.NewLog(mailFile)
+	}
+
+	var auditLog audit.Logger = audit.NewWriter(os.Stdout)
+	if configs.AuditLogFile != "" {
+		auditFile, err := os.OpenFile(configs.AuditLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			panic(err)
+		}
+		defer auditFile.Close()
+		auditLog = audit.NewWriter(auditFile)
+	}
+
+	// accounts are slowed down after each failure; IPs only lock, to spare clients sharing an address
+	failureWindow := time.Second * time.Duration(configs.LoginFailureWindow)
+	loginGuard := lockout.NewGuard(lockout.Policy{
+		MaxFailures: configs.LoginAccountMaxFailures,
+		Lockout:     time.Second * time.Duration(configs.LoginAccountLockout),
+		BaseDelay:   time.Second * time.Duration(configs.LoginDelayBase),
+		MaxDelay:    time.Second * time.Duration(configs.LoginDelayMax),
+		Window:      failureWindow,
+	}, lockout.Policy{
+		MaxFailures: configs.LoginIPMaxFailures,
+		Lockout:     time.Second * time.Duration(configs.LoginIPLockout),
+		Window:      failureWindow,
+	}, auditLog)
+	lockout.StartSweep(context.Background(), loginGuard, time.Minute)
+
+	// PASSWORD_HASHER=bcrypt keeps writing bcrypt; either way both kinds of hash verify, and logins
+	// upgrade hashes made with other settings
+	argon2id := password.Argon2id{
+		Memory:      configs.Argon2Memory,
+		Iterations:  configs.Argon2Iterations,
+		Parallelism: configs.Argon2Parallelism,
+		SaltLength:  password.DefaultArgon2id.SaltLength,
+		KeyLength:   password.DefaultArgon2id.KeyLength,
+	}
+	var passwordHasher password.Hasher = argon2id
+	if configs.PasswordHasher == "bcrypt" {
+		passwordHasher = password.Bcrypt{Cost: configs.BcryptCost}
+	} else if err := argon2id.Validate(); err != nil {
+		panic(err)
+	}
+	passwordPolicy := &password.Policy{MinLength: configs.PasswordMinLength}
+	if configs.PasswordBreachedFile != "" {
+		if err := passwordPolicy.LoadBreached(configs.PasswordBreachedFile); err != nil {
+			panic(err)
+		}
+	}
+	entities.ConfigurePasswords(passwordHasher, passwordPolicy)
+
+	err = bootstrapAdmin(context.Background(), userDB, configs.AdminName, configs.AdminEmail, configs.AdminPassword)
+	if err != nil {
+		panic(err)
+	}
 
-	productHandler := handlers.NewProductHandler(productDB)
-	userHandler := handlers.NewUserHandler(userDB)
+	// SESSION_COOKIES lets browser clients keep their tokens in HttpOnly cookies instead of script storage
+	var sessionCookies *session.Cookies
+	if configs.SessionCookies {
+		sessionCookies, err = session.New(configs.SessionCookieName, configs.SessionCookieDomain,
+			configs.SessionCookieSecure, configs.SessionCookieSameSite)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	consentCookies := session.Consent(configs.SessionCookieDomain, configs.SessionCookieSecure)
+
+	productHandler := handlers.NewProductHandler(productDB, userDB)
+	userHandler := handlers.NewUserHandler(userDB, refreshTokenDB, apiKeyDB, verificationTokenDB, recoveryCodeDB, challengeDB, transactionManager, revocations, mailer, loginGuard)
+	passwordResetHandler := handlers.NewPasswordResetHandler(userDB, passwordResetTokenDB, refreshTokenDB, transactionManager, revocations, mailer)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyDB)
+	oauthHandler := handlers.NewOAuthHandler(oauthClientDB, authorizationCodeDB, userDB, refreshTokenDB, revocations)
+	jwksHandler := handlers.NewJWKSHandler(configs.TokenAuth)
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.WithValue("jwt", configs.TokenAuth))
 	r.Use(middleware.WithValue("jwtExpiresIn", configs.JwtExpiresIn))
+	r.Use(middleware.WithValue("refreshTokenExpiresIn", configs.RefreshExpiresIn))
+	r.Use(middleware.WithValue("passwordResetExpiresIn", configs.PasswordResetExpiresIn))
+	r.Use(middleware.WithValue("verificationExpiresIn", configs.VerificationExpiresIn))
+	r.Use(middleware.WithValue("verificationResendInterval", configs.VerificationResend))
+	r.Use(middleware.WithValue("unverifiedLogin", configs.UnverifiedLogin))
+	r.Use(middleware.WithValue("twoFactorIssuer", configs.TwoFactorIssuer))
+	r.Use(middleware.WithValue("twoFactorChallengeExpiresIn", configs.TwoFactorChallengeExpiresIn))
+	r.Use(middleware.WithValue("oauthCodeExpiresIn", configs.OAuthCodeExpiresIn))
+	r.Use(middleware.WithValue("sessionCookies", sessionCookies))
+	r.Use(middleware.WithValue("consentCookies", consentCookies))
+	r.Use(middleware.WithValue("productsRequireIfMatch", configs.ProductsRequireIfMatch))
+	r.Use(middleware.WithValue("appBaseURL", strings.TrimSuffix(configs.AppBaseURL, "/")))
 	// r.Use(LogRequest)
 
 	r.Route("/products", func(r chi.Router) {
-		r.Use(jwtauth.Verifier(configs.TokenAuth))
-		r.Use(jwtauth.Authenticator)
-
-		r.Post("/", productHandler.CreateProduct)
-		r.Get("/", productHandler.GetProducts)
-		r.Get("/{id}", productHandler.GetProduct)
-		r.Put("/{id}", productHandler.UpdateProduct)
-		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Use(middlewares.Verifier(configs.TokenAuth, sessionCookies))
+		r.Use(middlewares.APIKey(apiKeyDB, userDB))
+		r.Use(middlewares.CSRF(sessionCookies))
+		r.Use(middlewares.RejectRevoked(revocations))
+		r.Use(middlewares.Authenticator)
+
+		r.With(middlewares.RequirePermission(entities.PermissionWriteProducts)).Post("/", productHandler.CreateProduct)
+		r.With(middlewares.RequirePermission(entities.PermissionReadProducts)).Get("/", productHandler.GetProducts)
+		r.With(middlewares.RequirePermission(entities.PermissionWriteProducts)).Get("/trash", productHandler.GetTrash)
+		r.With(middlewares.RequirePermission(entities.PermissionReadProducts)).Get("/{id}", productHandler.GetProduct)
+		r.With(middlewares.RequirePermission(entities.PermissionWriteProducts)).Put("/{id}", productHandler.UpdateProduct)
+		r.With(middlewares.RequirePermission(entities.PermissionWriteProducts)).Patch("/{id}", productHandler.PatchProduct)
+		r.With(middlewares.RequirePermission(entities.PermissionWriteProducts)).Delete("/{id}", productHandler.DeleteProduct)
+		r.With(middlewares.RequirePermission(entities.PermissionWriteProducts)).Post("/{id}/restore", productHandler.RestoreProduct)
+		r.With(middlewares.RequirePermission(entities.PermissionManageProducts)).Put("/{id}/owner", productHandler.TransferProduct)
 	})
 
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/", userHandler.CreateUser)
 		r.Post("/generate_token", userHandler.GetJWT)
+		r.Post("/generate_token/2fa", userHandler.CompleteTwoFactor)
+		r.Post("/refresh_token", userHandler.RefreshToken)
+		r.Post("/logout", userHandler.Logout)
+		r.Get("/verify", userHandler.VerifyEmail)
+		r.Post("/verify/resend", userHandler.ResendVerification)
+		r.Post("/password_reset", passwordResetHandler.RequestReset)
+		r.Post("/password_reset/confirm", passwordResetHandler.ConfirmReset)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.Verifier(configs.TokenAuth, sessionCookies))
+			r.Use(middlewares.CSRF(sessionCookies))
+			r.Use(middlewares.RejectRevoked(revocations))
+			r.Use(middlewares.Authenticator)
+			r.Use(middlewares.FirstPartyOnly)
+
+			r.Get("/me", userHandler.GetMe)
+			r.Patch("/me", userHandler.UpdateMe)
+			r.Delete("/me", userHandler.DeleteMe)
+			r.Put("/me/password", userHandler.ChangePassword)
+			r.Post("/me/2fa", userHandler.BeginTwoFactor)
+			r.Post("/me/2fa/confirm", userHandler.ConfirmTwoFactor)
+			r.Post("/me/2fa/disable", userHandler.DisableTwoFactor)
+			r.Post("/me/api_keys", apiKeyHandler.CreateAPIKey)
+			r.Get("/me/api_keys", apiKeyHandler.GetAPIKeys)
+			r.Delete("/me/api_keys/{id}", apiKeyHandler.RevokeAPIKey)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.Verifier(configs.TokenAuth, sessionCookies))
+			r.Use(middlewares.CSRF(sessionCookies))
+			r.Use(middlewares.RejectRevoked(revocations))
+			r.Use(middlewares.Authenticator)
+			r.Use(middlewares.FirstPartyOnly)
+			r.Use(middlewares.RequirePermission(entities.PermissionManageUsers))
+
+			r.Get("/{id}", userHandler.GetUser)
+			r.Patch("/{id}", userHandler.UpdateUser)
+			r.Delete("/{id}", userHandler.DeleteUser)
+			r.Put("/{id}/role", userHandler.UpdateRole)
+			r.Post("/{id}/revoke_tokens", userHandler.RevokeTokens)
+			r.Post("/{id}/unlock", userHandler.Unlock)
+		})
+	})
+
+	r.Route("/oauth", func(r chi.Router) {
+		r.Post("/token", oauthHandler.Token)
+		r.Post("/introspect", oauthHandler.Introspect)
+		r.Post("/revoke", oauthHandler.Revoke)
+
+		r.Get("/login", userHandler.LoginPage)
+		r.Post("/login", userHandler.Login)
+
+		// client applications send the browser here with a cross-site redirect, so it signs in with the
+		// Lax consent cookie and is sent to /oauth/login without one. The consent form carries its own
+		// token tied to the session, in place of the CSRF header.
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.Verifier(configs.TokenAuth, consentCookies))
+			r.Use(middlewares.LoginRedirect("/oauth/login", revocations))
+			r.Use(middlewares.FirstPartyOnly)
+
+			r.Get("/authorize", oauthHandler.Authorize)
+			r.Post("/authorize", oauthHandler.AuthorizeDecision)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.Verifier(configs.TokenAuth, sessionCookies))
+			r.Use(middlewares.CSRF(sessionCookies))
+			r.Use(middlewares.RejectRevoked(revocations))
+			r.Use(middlewares.Authenticator)
+			r.Use(middlewares.FirstPartyOnly)
+			r.Use(middlewares.RequirePermission(entities.PermissionManageUsers))
+
+			r.Post("/clients", oauthHandler.RegisterClient)
+			r.Get("/clients", oauthHandler.GetClients)
+			r.Delete("/clients/{id}", oauthHandler.DeleteClient)
+		})
 	})
 
+	r.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8080/docs/doc.json")))
 
 	http.ListenAndServe(":8080", r)
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+)
+
+const migrationsDir = "internal/infra/database/migrations"
+
+var errMigrateUsage = errors.New("usage: server migrate up | down [-steps n] | status | create <name> [-dir path]")
+
+// runMigrate implements `server migrate up|down|status|create`.
+func runMigrate(dbConfig database.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	command, args := args[0], args[1:]
+	if command == "create" {
+		flags := flag.NewFlagSet("create", flag.ContinueOnError)
+		dir := flags.String("dir", migrationsDir, "directory holding the migration files")
+		if len(args) == 0 {
+			return errMigrateUsage
+		}
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		path, err := migrations.Create(*dir, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", path)
+		return nil
+	}
+
+	db, err := database.NewConnection(dbConfig)
+	if err != nil {
+		return err
+	}
+	migrator := migrations.NewMigrator(db, migrations.All())
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "down":
+		flags := flag.NewFlagSet("down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+
+		reverted, err := migrator.Down(*steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return errMigrateUsage
+}
@@ -1,21 +1,73 @@
 package configs
 
 import (
-	"github.com/go-chi/jwtauth"
+	"strings"
+
+	"github.com/caiocp/go-api/internal/infra/jwks"
 	"github.com/spf13/viper"
 )
 
 type config struct {
-	DBDriver      string `mapstructure:"DB_DRIVER"`
-	DBHost        string `mapstructure:"DB_HOST"`
-	DBPort        string `mapstructure:"DB_PORT"`
-	DBUser        string `mapstructure:"DB_USER"`
-	DBPassword    string `mapstructure:"DB_PASSWORD"`
-	DBName        string `mapstructure:"DB_NAME"`
-	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
-	JwtSecret     string `mapstructure:"JWT_SECRET"`
-	JwtExpiresIn  int    `mapstructure:"JWT_EXPIRESIN"`
-	TokenAuth     *jwtauth.JWTAuth
+	DBDriver                    string `mapstructure:"DB_DRIVER"`
+	DBHost                      string `mapstructure:"DB_HOST"`
+	DBPort                      string `mapstructure:"DB_PORT"`
+	DBUser                      string `mapstructure:"DB_USER"`
+	DBPassword                  string `mapstructure:"DB_PASSWORD"`
+	DBName                      string `mapstructure:"DB_NAME"`
+	DBMaxOpenConns              int    `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns              int    `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime           int    `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBQueryTimeout              int    `mapstructure:"DB_QUERY_TIMEOUT"`
+	WebServerPort               string `mapstructure:"WEB_SERVER_PORT"`
+	JwtSecret                   string `mapstructure:"JWT_SECRET"`
+	JwtAlgorithm                string `mapstructure:"JWT_ALGORITHM"`
+	JwtPrivateKeys              string `mapstructure:"JWT_PRIVATE_KEYS"`
+	JwtExpiresIn                int    `mapstructure:"JWT_EXPIRESIN"`
+	RefreshExpiresIn            int    `mapstructure:"REFRESH_TOKEN_EXPIRESIN"`
+	RevocationStore             string `mapstructure:"REVOCATION_STORE"`
+	RevocationGC                int    `mapstructure:"REVOCATION_GC_INTERVAL"`
+	PasswordResetExpiresIn      int    `mapstructure:"PASSWORD_RESET_EXPIRESIN"`
+	VerificationExpiresIn       int    `mapstructure:"VERIFICATION_EXPIRESIN"`
+	VerificationResend          int    `mapstructure:"VERIFICATION_RESEND_INTERVAL"`
+	UnverifiedLogin             string `mapstructure:"UNVERIFIED_LOGIN"`
+	AppBaseURL                  string `mapstructure:"APP_BASE_URL"`
+	TwoFactorIssuer             string `mapstructure:"TWO_FACTOR_ISSUER"`
+	TwoFactorChallengeExpiresIn int    `mapstructure:"TWO_FACTOR_CHALLENGE_EXPIRESIN"`
+	OAuthCodeExpiresIn          int    `mapstructure:"OAUTH_CODE_EXPIRESIN"`
+	ProductsRequireIfMatch      bool   `mapstructure:"PRODUCTS_REQUIRE_IF_MATCH"`
+	ProductTrashRetention       int    `mapstructure:"PRODUCT_TRASH_RETENTION"`
+	ProductPurgeInterval        int    `mapstructure:"PRODUCT_PURGE_INTERVAL"`
+	SessionCookies              bool   `mapstructure:"SESSION_COOKIES"`
+	SessionCookieName           string `mapstructure:"SESSION_COOKIE_NAME"`
+	SessionCookieDomain         string `mapstructure:"SESSION_COOKIE_DOMAIN"`
+	SessionCookieSecure         bool   `mapstructure:"SESSION_COOKIE_SECURE"`
+	SessionCookieSameSite       string `mapstructure:"SESSION_COOKIE_SAMESITE"`
+	LoginAccountMaxFailures     int    `mapstructure:"LOGIN_ACCOUNT_MAX_FAILURES"`
+	LoginAccountLockout         int    `mapstructure:"LOGIN_ACCOUNT_LOCKOUT"`
+	LoginIPMaxFailures          int    `mapstructure:"LOGIN_IP_MAX_FAILURES"`
+	LoginIPLockout              int    `mapstructure:"LOGIN_IP_LOCKOUT"`
+	LoginDelayBase              int    `mapstructure:"LOGIN_DELAY_BASE"`
+	LoginDelayMax               int    `mapstructure:"LOGIN_DELAY_MAX"`
+	LoginFailureWindow          int    `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	AuditLogFile                string `mapstructure:"AUDIT_LOG_FILE"`
+	PasswordHasher              string `mapstructure:"PASSWORD_HASHER"`
+	Argon2Memory                uint32 `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations            uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism           uint8  `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost                  int    `mapstructure:"BCRYPT_COST"`
+	PasswordMinLength           int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordBreachedFile        string `mapstructure:"PASSWORD_BREACHED_FILE"`
+	MailDriver                  string `mapstructure:"MAIL_DRIVER"`
+	MailFile                    string `mapstructure:"MAIL_FILE"`
+	MailFrom                    string `mapstructure:"MAIL_FROM"`
+	SMTPHost                    string `mapstructure:"SMTP_HOST"`
+	SMTPPort                    string `mapstructure:"SMTP_PORT"`
+	SMTPUsername                string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword                string `mapstructure:"SMTP_PASSWORD"`
+	AdminName                   string `mapstructure:"ADMIN_NAME"`
+	AdminEmail                  string `mapstructure:"ADMIN_EMAIL"`
+	AdminPassword               string `mapstructure:"ADMIN_PASSWORD"`
+	TokenAuth                   *jwks.KeySet
 }
 
 func LoadConfig(path string) (*config, error) {
@@ -26,6 +78,41 @@ func LoadConfig(path string) (*config, error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv()
+	viper.SetDefault("JWT_ALGORITHM", "HS256")
+	viper.SetDefault("REFRESH_TOKEN_EXPIRESIN", 30*24*60*60)
+	viper.SetDefault("REVOCATION_STORE", "database")
+	viper.SetDefault("REVOCATION_GC_INTERVAL", 10*60)
+	viper.SetDefault("PASSWORD_RESET_EXPIRESIN", 60*60)
+	viper.SetDefault("VERIFICATION_EXPIRESIN", 24*60*60)
+	viper.SetDefault("VERIFICATION_RESEND_INTERVAL", 60)
+	viper.SetDefault("UNVERIFIED_LOGIN", "deny")
+	viper.SetDefault("APP_BASE_URL", "http://localhost:8080")
+	viper.SetDefault("TWO_FACTOR_ISSUER", "Go API")
+	viper.SetDefault("TWO_FACTOR_CHALLENGE_EXPIRESIN", 5*60)
+	viper.SetDefault("OAUTH_CODE_EXPIRESIN", 60)
+	viper.SetDefault("PRODUCTS_REQUIRE_IF_MATCH", true)
+	viper.SetDefault("PRODUCT_TRASH_RETENTION", 30*24*60*60)
+	viper.SetDefault("PRODUCT_PURGE_INTERVAL", 60*60)
+	viper.SetDefault("SESSION_COOKIES", false)
+	viper.SetDefault("SESSION_COOKIE_NAME", "session")
+	viper.SetDefault("SESSION_COOKIE_SECURE", true)
+	viper.SetDefault("SESSION_COOKIE_SAMESITE", "strict")
+	viper.SetDefault("LOGIN_ACCOUNT_MAX_FAILURES", 5)
+	viper.SetDefault("LOGIN_ACCOUNT_LOCKOUT", 15*60)
+	viper.SetDefault("LOGIN_IP_MAX_FAILURES", 50)
+	viper.SetDefault("LOGIN_IP_LOCKOUT", 15*60)
+	viper.SetDefault("LOGIN_DELAY_BASE", 1)
+	viper.SetDefault("LOGIN_DELAY_MAX", 30)
+	viper.SetDefault("LOGIN_FAILURE_WINDOW", 15*60)
+	viper.SetDefault("PASSWORD_HASHER", "argon2id")
+	viper.SetDefault("ARGON2_MEMORY", 19*1024)
+	viper.SetDefault("ARGON2_ITERATIONS", 2)
+	viper.SetDefault("ARGON2_PARALLELISM", 1)
+	viper.SetDefault("BCRYPT_COST", 10)
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("MAIL_DRIVER", "log")
+	viper.SetDefault("MAIL_FROM", "no-reply@localhost")
+	viper.SetDefault("SMTP_PORT", "25")
 	err := viper.ReadInConfig()
 	if err != nil {
 		panic(err)
@@ -36,7 +123,15 @@ func LoadConfig(path string) (*config, error) {
 		panic(err)
 	}
 
-	cfg.TokenAuth = jwtauth.New("HS256", []byte(cfg.JwtSecret), nil)
+	if strings.HasPrefix(cfg.JwtAlgorithm, "HS") {
+		cfg.TokenAuth, err = jwks.NewHMAC(cfg.JwtAlgorithm, []byte(cfg.JwtSecret))
+	} else {
+		// JWT_PRIVATE_KEYS lists PEM files, the first one signs and the rest are kept for verification
+		cfg.TokenAuth, err = jwks.LoadPEM(cfg.JwtAlgorithm, strings.Split(cfg.JwtPrivateKeys, ","))
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
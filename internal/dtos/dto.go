@@ -1,21 +1,174 @@
 package dtos
 
+import (
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+)
+
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name  string  `json:"name" validate:"required,max=255"`
+	Price float64 `json:"price" validate:"required,gt=0"`
+}
+
+type TransferProductInput struct {
+	OwnerID string `json:"owner_id" validate:"required"`
 }
 
 type CreateUserInput struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+func (i *CreateUserInput) Normalize() {
+	i.Email = entities.NormalizeEmail(i.Email)
 }
 
 type GetJWTInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	Cookie   bool   `json:"cookie"`
+}
+
+func (i *GetJWTInput) Normalize() {
+	i.Email = entities.NormalizeEmail(i.Email)
+}
+
+type UpdateUserInput struct {
+	Name  *string `json:"name" validate:"min=1,max=100"`
+	Email *string `json:"email" validate:"email,max=255"`
+}
+
+func (i *UpdateUserInput) Normalize() {
+	if i.Email != nil {
+		email := entities.NormalizeEmail(*i.Email)
+		i.Email = &email
+	}
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,max=72"`
+}
+
+type PasswordResetInput struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+func (i *PasswordResetInput) Normalize() {
+	i.Email = entities.NormalizeEmail(i.Email)
+}
+
+type ConfirmPasswordResetInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,max=72"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+func (i *ResendVerificationInput) Normalize() {
+	i.Email = entities.NormalizeEmail(i.Email)
+}
+
+type UpdateUserRoleInput struct {
+	Role string `json:"role" validate:"required,oneof=admin editor viewer"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorInput struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Code            string `json:"code" validate:"required"`
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+	Cookie         bool   `json:"cookie"`
+}
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateAPIKeyOutput struct {
+	entities.APIKey
+	Key string `json:"key"`
+}
+
+type RegisterOAuthClientInput struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+	Public       bool     `json:"public"`
+}
+
+type OAuthClientOutput struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types"`
+	Scope        string    `json:"scope"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type OAuthTokenOutput struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+type OAuthErrorOutput struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type OAuthIntrospectionOutput struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	JwtID     string `json:"jti,omitempty"`
+}
+
+type TwoFactorEnrollmentOutput struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorChallengeOutput struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int    `json:"expires_in"`
+}
+
+type SessionOutput struct {
+	CSRFToken string `json:"csrf_token"`
 }
 
 type GetJwtOutput struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
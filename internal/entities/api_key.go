@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+)
+
+const apiKeyPrefix = "gak_"
+
+var (
+	ErrInvalidAPIKey    = errors.New("invalid, expired or revoked API key")
+	ErrInvalidExpiresAt = errors.New("expires_at must be in the future")
+)
+
+// APIKey is a long-lived credential a user creates for scripts and integrations. The full key is
+// shown once; only its SHA-256 is stored, next to a short prefix that identifies it in listings.
+// An empty Scope leaves the key with every permission of the user's role, otherwise the key is
+// limited to the listed permissions.
+type APIKey struct {
+	ID         entities.ID `json:"id" gorm:"size:36"`
+	UserID     entities.ID `json:"-" gorm:"size:36;index"`
+	Name       string      `json:"name" gorm:"size:100"`
+	Prefix     string      `json:"prefix" gorm:"size:16"`
+	KeyHash    string      `json:"-" gorm:"size:64;uniqueIndex"`
+	Scope      string      `json:"scope" gorm:"size:255"`
+	ExpiresAt  *time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time  `json:"revoked_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// NewAPIKey returns the key to store and the plain value to hand to the user once.
+func NewAPIKey(userID entities.ID, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error) {
+	permissions := make([]Permission, len(scopes))
+	for i, s := range scopes {
+		p, err := ParsePermission(s)
+		if err != nil {
+			return nil, "", err
+		}
+		permissions[i] = p
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", ErrInvalidExpiresAt
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	prefix := apiKeyPrefix + hex.EncodeToString(id)
+	plain := prefix + "_" + secret
+
+	return &APIKey{
+		ID:        entities.NewID(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   HashAPIKey(plain),
+		Scope:     FormatScope(permissions),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func HashAPIKey(plain string) string {
+	return hashToken(strings.TrimSpace(plain))
+}
+
+// IsActive reports whether the key is neither revoked nor expired.
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
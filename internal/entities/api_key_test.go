@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	userID := entities.NewID()
+	expiresAt := time.Now().Add(time.Hour)
+
+	key, plain, err := NewAPIKey(userID, "ci", []string{"products:read", "products:write"}, &expiresAt)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, key.Prefix+"_"))
+	assert.Regexp(t, `^gak_[0-9a-f]{8}$`, key.Prefix)
+	assert.Equal(t, HashAPIKey(plain), key.KeyHash)
+	assert.Equal(t, "products:read products:write", key.Scope)
+	assert.Equal(t, userID, key.UserID)
+	assert.True(t, key.IsActive())
+
+	unscoped, _, err := NewAPIKey(userID, "ci", nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, unscoped.Scope)
+	assert.True(t, unscoped.IsActive())
+}
+
+func TestNewAPIKeyRejectsInvalidInput(t *testing.T) {
+	_, _, err := NewAPIKey(entities.NewID(), "ci", []string{"products:delete"}, nil)
+	assert.Equal(t, ErrInvalidScope, err)
+
+	past := time.Now().Add(-time.Minute)
+	_, _, err = NewAPIKey(entities.NewID(), "ci", nil, &past)
+	assert.Equal(t, ErrInvalidExpiresAt, err)
+}
+
+func TestAPIKeyIsActive(t *testing.T) {
+	key, _, _ := NewAPIKey(entities.NewID(), "ci", nil, nil)
+
+	past := time.Now().Add(-time.Minute)
+	key.ExpiresAt = &past
+	assert.False(t, key.IsActive())
+
+	key.ExpiresAt = nil
+	now := time.Now()
+	key.RevokedAt = &now
+	assert.False(t, key.IsActive())
+}
@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"errors"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+)
+
+var ErrInvalidVerificationToken = errors.New("verification token is invalid or has expired")
+
+// EmailVerificationToken confirms that the owner of a user account can read mail sent to Email.
+// Keeping the address lets a link sent before an email change be refused. Only the SHA-256 of the
+// token is stored.
+type EmailVerificationToken struct {
+	ID        entities.ID `json:"id" gorm:"size:36"`
+	UserID    entities.ID `json:"user_id" gorm:"size:36;index"`
+	Email     string      `json:"email" gorm:"size:255"`
+	TokenHash string      `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	UsedAt    *time.Time  `json:"used_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewEmailVerificationToken returns the token to store and the plain value to send to the user.
+func NewEmailVerificationToken(user *User, ttl time.Duration) (*EmailVerificationToken, string, error) {
+	plain, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &EmailVerificationToken{
+		ID:        entities.NewID(),
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: HashEmailVerificationToken(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func HashEmailVerificationToken(plain string) string {
+	return hashToken(plain)
+}
+
+func (t *EmailVerificationToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *EmailVerificationToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
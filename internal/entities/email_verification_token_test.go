@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEmailVerificationToken(t *testing.T) {
+	user, _ := NewUser("John Doe", "email@example.com", "12345678")
+
+	token, plain, err := NewEmailVerificationToken(user, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, user.ID, token.UserID)
+	assert.Equal(t, user.Email, token.Email)
+	assert.Equal(t, HashEmailVerificationToken(plain), token.TokenHash)
+	assert.False(t, token.IsExpired())
+	assert.False(t, token.IsUsed())
+
+	expired, _, _ := NewEmailVerificationToken(user, -time.Second)
+	assert.True(t, expired.IsExpired())
+}
@@ -0,0 +1,247 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+)
+
+// OAuth2 grant types a client may be registered for.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// PKCEMethodS256 is the only code_challenge_method accepted; plain challenges are refused.
+const PKCEMethodS256 = "S256"
+
+var grantTypes = []string{GrantClientCredentials, GrantAuthorizationCode, GrantRefreshToken}
+
+// OAuthScopes are the permissions third-party clients can be granted. Managing users stays with
+// first-party sessions.
+var OAuthScopes = []Permission{PermissionReadProducts, PermissionWriteProducts}
+
+var (
+	ErrInvalidGrantType   = errors.New("invalid grant type for this client")
+	ErrInvalidRedirectURI = errors.New("redirect URIs must be absolute http(s) URLs without a fragment")
+	ErrInvalidClient      = errors.New("client authentication failed")
+	ErrInvalidGrant       = errors.New("authorization grant is invalid, expired, used or was issued to another client")
+)
+
+// OAuthClient is a third-party application registered to obtain tokens from /oauth/token.
+// Confidential clients authenticate with a secret of which only the SHA-256 is stored; public
+// clients have none and can only use the authorization code grant, always with PKCE. Lists are
+// stored space separated.
+type OAuthClient struct {
+	ID           entities.ID `json:"client_id" gorm:"size:36"`
+	Name         string      `json:"name" gorm:"size:100"`
+	SecretHash   string      `json:"-" gorm:"size:64"`
+	RedirectURIs string      `json:"redirect_uris" gorm:"size:2048"`
+	GrantTypes   string      `json:"grant_types" gorm:"size:255"`
+	Scope        string      `json:"scope" gorm:"size:255"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// NewOAuthClient returns the client to store and, for confidential clients, the plain secret to
+// hand to its developer once. Without scopes the client may request every OAuth scope.
+func NewOAuthClient(name string, redirectURIs, grants, scopes []string, confidential bool) (*OAuthClient, string, error) {
+	if len(grants) == 0 {
+		return nil, "", ErrInvalidGrantType
+	}
+	for _, g := range grants {
+		if !containsString(grantTypes, g) || g == GrantClientCredentials && !confidential {
+			return nil, "", ErrInvalidGrantType
+		}
+	}
+	if containsString(grants, GrantAuthorizationCode) && len(redirectURIs) == 0 {
+		return nil, "", ErrInvalidRedirectURI
+	}
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.Fragment != "" {
+			return nil, "", ErrInvalidRedirectURI
+		}
+	}
+
+	permissions := OAuthScopes
+	if len(scopes) > 0 {
+		permissions = make([]Permission, len(scopes))
+		for i, s := range scopes {
+			p, err := ParsePermission(s)
+			if err != nil || !ScopeAllows(FormatScope(OAuthScopes), p) {
+				return nil, "", ErrInvalidScope
+			}
+			permissions[i] = p
+		}
+	}
+
+	client := &OAuthClient{
+		ID:           entities.NewID(),
+		Name:         name,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		GrantTypes:   strings.Join(grants, " "),
+		Scope:        FormatScope(permissions),
+		CreatedAt:    time.Now(),
+	}
+
+	if !confidential {
+		return client, "", nil
+	}
+
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	client.SecretHash = hashToken(secret)
+
+	return client, secret, nil
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+func (c *OAuthClient) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// Authenticate checks the secret of a confidential client. Public clients must not send one.
+func (c *OAuthClient) Authenticate(secret string) bool {
+	if !c.IsConfidential() {
+		return secret == ""
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(c.SecretHash)) == 1
+}
+
+func (c *OAuthClient) AllowsGrant(grant string) bool {
+	return containsString(strings.Fields(c.GrantTypes), grant)
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered redirect URI.
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	return containsString(strings.Fields(c.RedirectURIs), uri)
+}
+
+// DefaultRedirectURI is the redirect URI to use when a request omits it, which is only allowed when
+// the client registered exactly one.
+func (c *OAuthClient) DefaultRedirectURI() (string, bool) {
+	uris := strings.Fields(c.RedirectURIs)
+	if len(uris) != 1 {
+		return "", false
+	}
+
+	return uris[0], true
+}
+
+// GrantScope narrows a requested scope to what the client is registered for. An empty request
+// means every registered scope.
+func (c *OAuthClient) GrantScope(requested string) (string, error) {
+	return narrowScope(c.Scope, requested)
+}
+
+// AuthorizationCode is the single-use code handed to a client through the user's browser after
+// consent. It is bound to the client, the redirect URI and the PKCE challenge it was issued for.
+type AuthorizationCode struct {
+	ID            entities.ID `json:"id" gorm:"size:36"`
+	CodeHash      string      `json:"-" gorm:"size:64;uniqueIndex"`
+	ClientID      entities.ID `json:"client_id" gorm:"size:36"`
+	UserID        entities.ID `json:"user_id" gorm:"size:36"`
+	RedirectURI   string      `json:"redirect_uri" gorm:"size:2048"`
+	Scope         string      `json:"scope" gorm:"size:255"`
+	CodeChallenge string      `json:"-" gorm:"size:128"`
+	ExpiresAt     time.Time   `json:"expires_at"`
+	UsedAt        *time.Time  `json:"used_at"`
+	// FamilyID and TokenID name the refresh token family and the access token the code was exchanged
+	// for, so that both can be revoked if the code is presented again.
+	FamilyID  *entities.ID `json:"-" gorm:"size:36"`
+	TokenID   string       `json:"-" gorm:"size:36"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// NewAuthorizationCode returns the code to store and the plain value to send to the redirect URI.
+func NewAuthorizationCode(clientID, userID entities.ID, redirectURI, scope, codeChallenge string, ttl time.Duration) (*AuthorizationCode, string, error) {
+	plain, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &AuthorizationCode{
+		ID:            entities.NewID(),
+		CodeHash:      HashAuthorizationCode(plain),
+		ClientID:      clientID,
+		UserID:        userID,
+		RedirectURI:   redirectURI,
+		Scope:         scope,
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     now.Add(ttl),
+		CreatedAt:     now,
+	}, plain, nil
+}
+
+func HashAuthorizationCode(plain string) string {
+	return hashToken(plain)
+}
+
+func (c *AuthorizationCode) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+func (c *AuthorizationCode) IsUsed() bool {
+	return c.UsedAt != nil
+}
+
+// VerifyPKCE checks an RFC 7636 code_verifier against the stored S256 challenge.
+func (c *AuthorizationCode) VerifyPKCE(verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(PKCEChallenge(verifier)), []byte(c.CodeChallenge)) == 1
+}
+
+// PKCEChallenge derives the S256 code_challenge for verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// narrowScope returns requested when every scope in it is listed in granted, or granted itself when
+// nothing is requested.
+func narrowScope(granted, requested string) (string, error) {
+	fields := strings.Fields(requested)
+	if len(fields) == 0 {
+		return granted, nil
+	}
+
+	var permissions []Permission
+	for _, s := range fields {
+		p := Permission(s)
+		if !ScopeAllows(granted, p) {
+			return "", ErrInvalidScope
+		}
+		if !ScopeAllows(FormatScope(permissions), p) {
+			permissions = append(permissions, p)
+		}
+	}
+
+	return FormatScope(permissions), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
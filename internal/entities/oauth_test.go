@@ -0,0 +1,104 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOAuthClient(t *testing.T) {
+	client, secret, err := NewOAuthClient("Partner", []string{"https://partner.example.com/callback"},
+		[]string{GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials}, []string{"products:read"}, true)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	assert.True(t, client.IsConfidential())
+	assert.True(t, client.Authenticate(secret))
+	assert.False(t, client.Authenticate("wrong"))
+	assert.False(t, client.Authenticate(""))
+	assert.True(t, client.AllowsGrant(GrantClientCredentials))
+	assert.True(t, client.AllowsRedirectURI("https://partner.example.com/callback"))
+	assert.False(t, client.AllowsRedirectURI("https://partner.example.com/callback/"))
+	assert.Equal(t, "products:read", client.Scope)
+
+	uri, ok := client.DefaultRedirectURI()
+	assert.True(t, ok)
+	assert.Equal(t, "https://partner.example.com/callback", uri)
+
+	public, secret, err := NewOAuthClient("SPA", []string{"http://localhost:3000/cb"}, []string{GrantAuthorizationCode}, nil, false)
+	assert.NoError(t, err)
+	assert.Empty(t, secret)
+	assert.False(t, public.IsConfidential())
+	assert.True(t, public.Authenticate(""))
+	assert.False(t, public.AllowsGrant(GrantRefreshToken))
+	assert.Equal(t, "products:read products:write", public.Scope)
+}
+
+func TestNewOAuthClientRejectsInvalidMetadata(t *testing.T) {
+	cases := []struct {
+		name         string
+		redirectURIs []string
+		grants       []string
+		scopes       []string
+		confidential bool
+		err          error
+	}{
+		{"no grants", nil, nil, nil, true, ErrInvalidGrantType},
+		{"unknown grant", nil, []string{"password"}, nil, true, ErrInvalidGrantType},
+		{"public client credentials", nil, []string{GrantClientCredentials}, nil, false, ErrInvalidGrantType},
+		{"code without redirect", nil, []string{GrantAuthorizationCode}, nil, true, ErrInvalidRedirectURI},
+		{"relative redirect", []string{"/callback"}, []string{GrantAuthorizationCode}, nil, true, ErrInvalidRedirectURI},
+		{"fragment", []string{"https://a.example.com/cb#x"}, []string{GrantAuthorizationCode}, nil, true, ErrInvalidRedirectURI},
+		{"custom scheme", []string{"javascript://a.example.com"}, []string{GrantAuthorizationCode}, nil, true, ErrInvalidRedirectURI},
+		{"users scope", nil, []string{GrantClientCredentials}, []string{"users:manage"}, true, ErrInvalidScope},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := NewOAuthClient("client", c.redirectURIs, c.grants, c.scopes, c.confidential)
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
+
+func TestOAuthClientGrantScope(t *testing.T) {
+	client, _, _ := NewOAuthClient("Partner", nil, []string{GrantClientCredentials}, nil, true)
+
+	scope, err := client.GrantScope("")
+	assert.NoError(t, err)
+	assert.Equal(t, "products:read products:write", scope)
+
+	scope, err = client.GrantScope("products:read products:read")
+	assert.NoError(t, err)
+	assert.Equal(t, "products:read", scope)
+
+	_, err = client.GrantScope("products:read users:manage")
+	assert.Equal(t, ErrInvalidScope, err)
+}
+
+func TestAuthorizationCodePKCE(t *testing.T) {
+	// RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", PKCEChallenge(verifier))
+
+	code, plain, err := NewAuthorizationCode(entities.NewID(), entities.NewID(), "https://a.example.com/cb",
+		"products:read", PKCEChallenge(verifier), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, HashAuthorizationCode(plain), code.CodeHash)
+	assert.False(t, code.IsExpired())
+	assert.False(t, code.IsUsed())
+	assert.True(t, code.VerifyPKCE(verifier))
+	assert.False(t, code.VerifyPKCE(verifier[:42]))
+	assert.False(t, code.VerifyPKCE("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXX"))
+}
+
+func TestRefreshTokenNarrowScope(t *testing.T) {
+	token := &RefreshToken{Scope: "products:read products:write"}
+
+	scope, err := token.NarrowScope("products:write")
+	assert.NoError(t, err)
+	assert.Equal(t, "products:write", scope)
+
+	_, err = (&RefreshToken{Scope: "products:read"}).NarrowScope("products:write")
+	assert.Equal(t, ErrInvalidScope, err)
+}
@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"errors"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+)
+
+var ErrInvalidPasswordResetToken = errors.New("password reset token is invalid or has expired")
+
+// PasswordResetToken lets the owner of an email address set a new password once, before ExpiresAt.
+// Only the SHA-256 of the token is stored.
+type PasswordResetToken struct {
+	ID        entities.ID `json:"id" gorm:"size:36"`
+	UserID    entities.ID `json:"user_id" gorm:"size:36;index"`
+	TokenHash string      `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	UsedAt    *time.Time  `json:"used_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewPasswordResetToken returns the token to store and the plain value to send to the user.
+func NewPasswordResetToken(userID entities.ID, ttl time.Duration) (*PasswordResetToken, string, error) {
+	plain, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &PasswordResetToken{
+		ID:        entities.NewID(),
+		UserID:    userID,
+		TokenHash: HashPasswordResetToken(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func HashPasswordResetToken(plain string) string {
+	return hashToken(plain)
+}
+
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *PasswordResetToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	userID := entities.NewID()
+
+	token, plain, err := NewPasswordResetToken(userID, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, HashPasswordResetToken(plain), token.TokenHash)
+	assert.NotEqual(t, plain, token.TokenHash)
+	assert.False(t, token.IsExpired())
+	assert.False(t, token.IsUsed())
+
+	expired, _, _ := NewPasswordResetToken(userID, -time.Second)
+	assert.True(t, expired.IsExpired())
+}
@@ -13,13 +13,23 @@ var (
 	ErrNameIsRequired  = errors.New("name is required")
 	ErrPriceIsRequired = errors.New("price is required")
 	ErrInvalidPrice    = errors.New("invalid price")
+	ErrInvalidOwner    = errors.New("owner must be an existing user")
+	ErrProductModified = errors.New("product was changed by another request")
 )
 
 type Product struct {
-	ID        entities.ID `json:"id"`
+	ID        entities.ID `json:"id" gorm:"size:36"`
 	Name      string      `json:"name"`
 	Price     float64     `json:"price"`
 	CreatedAt time.Time   `json:"created_at"`
+
+	// OwnerID is nil for products created before ownership was recorded, which only admins can change.
+	OwnerID *entities.ID `json:"owner_id" gorm:"size:36;index"`
+	// Version goes up with every change and backs the ETag of the product.
+	Version int `json:"version" gorm:"not null;default:1"`
+	// DeletedAt is set while the product is in the trash, from where it can be restored until it is
+	// purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 func NewProduct(name string, price float64) (*Product, error) {
@@ -28,6 +38,7 @@ func NewProduct(name string, price float64) (*Product, error) {
 		Name:      name,
 		Price:     price,
 		CreatedAt: time.Now(),
+		Version:   1,
 	}
 
 	if err := product.Validate(); err != nil {
@@ -56,3 +67,13 @@ func (p *Product) Validate() error {
 
 	return nil
 }
+
+// IsOwnedBy reports whether the user with userID owns the product.
+func (p *Product) IsOwnedBy(userID string) bool {
+	return p.OwnerID != nil && p.OwnerID.String() == userID
+}
+
+// IsDeleted reports whether the product is in the trash.
+func (p *Product) IsDeleted() bool {
+	return p.DeletedAt != nil
+}
@@ -3,6 +3,7 @@ package entities
 import (
 	"testing"
 
+	"github.com/caiocp/go-api/pkg/entities"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,6 +14,7 @@ func TestNewProduct(t *testing.T) {
 	assert.NotEmpty(t, product.ID)
 	assert.Equal(t, "Product 1", product.Name)
 	assert.Equal(t, 100.0, product.Price)
+	assert.Equal(t, 1, product.Version)
 }
 
 func TestProductWhenNameIsRequired(t *testing.T) {
@@ -42,3 +44,16 @@ func TestProductValidate(t *testing.T) {
 	assert.NotNil(t, product)
 	assert.Nil(t, product.Validate())
 }
+
+func TestProductIsOwnedBy(t *testing.T) {
+	product, err := NewProduct("Product 1", 10)
+	assert.Nil(t, err)
+
+	owner := entities.NewID()
+	assert.False(t, product.IsOwnedBy(owner.String()), "products without an owner belong to nobody")
+
+	product.OwnerID = &owner
+	assert.True(t, product.IsOwnedBy(owner.String()))
+	assert.False(t, product.IsOwnedBy(entities.NewID().String()))
+	assert.False(t, product.IsOwnedBy(""))
+}
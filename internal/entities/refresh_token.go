@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"errors"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// RefreshToken is a long-lived, single-use credential exchanged for a new access token. Only the
+// SHA-256 of the token is stored. Tokens rotated from the same login share a FamilyID so the whole
+// chain can be revoked when an old token is replayed. Tokens issued through OAuth2 record the client
+// and the scope granted to it; first-party tokens leave both empty.
+type RefreshToken struct {
+	ID        entities.ID `json:"id" gorm:"size:36"`
+	UserID    entities.ID `json:"user_id" gorm:"size:36;index"`
+	FamilyID  entities.ID `json:"family_id" gorm:"size:36;index"`
+	TokenHash string      `json:"-" gorm:"size:64;uniqueIndex"`
+	ClientID  string      `json:"client_id,omitempty" gorm:"size:36"`
+	Scope     string      `json:"scope,omitempty" gorm:"size:255"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	RevokedAt *time.Time  `json:"revoked_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewRefreshToken returns the token to store and the plain value to hand to the client.
+func NewRefreshToken(userID, familyID entities.ID, ttl time.Duration) (*RefreshToken, string, error) {
+	plain, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &RefreshToken{
+		ID:        entities.NewID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashRefreshToken(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func HashRefreshToken(plain string) string {
+	return hashToken(plain)
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// NarrowScope applies the scope parameter of an OAuth2 refresh_token grant, which may only drop
+// scopes from the original grant.
+func (t *RefreshToken) NarrowScope(requested string) (string, error) {
+	return narrowScope(t.Scope, requested)
+}
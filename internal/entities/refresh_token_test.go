@@ -0,0 +1,33 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	userID, familyID := entities.NewID(), entities.NewID()
+
+	token, plain, err := NewRefreshToken(userID, familyID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, familyID, token.FamilyID)
+	assert.Equal(t, HashRefreshToken(plain), token.TokenHash)
+	assert.NotEqual(t, plain, token.TokenHash)
+	assert.False(t, token.IsExpired())
+	assert.False(t, token.IsRevoked())
+
+	_, other, err := NewRefreshToken(userID, familyID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, plain, other)
+}
+
+func TestRefreshTokenIsExpired(t *testing.T) {
+	token, _, err := NewRefreshToken(entities.NewID(), entities.NewID(), -time.Second)
+	assert.Nil(t, err)
+	assert.True(t, token.IsExpired())
+}
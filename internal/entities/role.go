@@ -0,0 +1,93 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+type Permission string
+
+const (
+	PermissionReadProducts  Permission = "products:read"
+	PermissionWriteProducts Permission = "products:write"
+	// PermissionManageProducts lifts the ownership rule: products of every owner can be changed,
+	// deleted and handed to another owner.
+	PermissionManageProducts Permission = "products:manage"
+	PermissionManageUsers    Permission = "users:manage"
+)
+
+var permissions = []Permission{PermissionReadProducts, PermissionWriteProducts, PermissionManageProducts, PermissionManageUsers}
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:  {PermissionReadProducts, PermissionWriteProducts, PermissionManageProducts, PermissionManageUsers},
+	RoleEditor: {PermissionReadProducts, PermissionWriteProducts},
+	RoleViewer: {PermissionReadProducts},
+}
+
+// UnverifiedPermissions is all an account may do before its email address is confirmed, when
+// unverified accounts are allowed to log in at all.
+var UnverifiedPermissions = []Permission{PermissionReadProducts}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", ErrInvalidRole
+	}
+
+	return role, nil
+}
+
+func ParsePermission(s string) (Permission, error) {
+	for _, p := range permissions {
+		if Permission(s) == p {
+			return p, nil
+		}
+	}
+
+	return "", ErrInvalidScope
+}
+
+// Can reports whether the role grants the permission. Unknown roles grant nothing.
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FormatScope renders permissions as a space separated scope claim.
+func FormatScope(permissions []Permission) string {
+	scopes := make([]string, len(permissions))
+	for i, p := range permissions {
+		scopes[i] = string(p)
+	}
+
+	return strings.Join(scopes, " ")
+}
+
+// ScopeAllows reports whether the space separated scope lists the permission.
+func ScopeAllows(scope string, permission Permission) bool {
+	for _, s := range strings.Fields(scope) {
+		if Permission(s) == permission {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("editor")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleEditor, role)
+
+	_, err = ParseRole("root")
+	assert.Equal(t, ErrInvalidRole, err)
+}
+
+func TestRoleCan(t *testing.T) {
+	assert.True(t, RoleAdmin.Can(PermissionManageUsers))
+	assert.True(t, RoleEditor.Can(PermissionWriteProducts))
+	assert.False(t, RoleEditor.Can(PermissionManageUsers))
+	assert.True(t, RoleAdmin.Can(PermissionManageProducts))
+	assert.False(t, RoleEditor.Can(PermissionManageProducts))
+	assert.True(t, RoleViewer.Can(PermissionReadProducts))
+	assert.False(t, RoleViewer.Can(PermissionWriteProducts))
+	assert.False(t, Role("").Can(PermissionReadProducts))
+}
+
+func TestScope(t *testing.T) {
+	scope := FormatScope([]Permission{PermissionReadProducts, PermissionWriteProducts})
+	assert.Equal(t, "products:read products:write", scope)
+	assert.True(t, ScopeAllows(scope, PermissionWriteProducts))
+	assert.False(t, ScopeAllows(scope, PermissionManageUsers))
+	assert.False(t, ScopeAllows("", PermissionReadProducts))
+}
+
+func TestParsePermission(t *testing.T) {
+	permission, err := ParsePermission("products:write")
+	assert.NoError(t, err)
+	assert.Equal(t, PermissionWriteProducts, permission)
+
+	_, err = ParsePermission("products:delete")
+	assert.Equal(t, ErrInvalidScope, err)
+}
@@ -0,0 +1,19 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+)
+
+// TokenRevocation blocks access tokens before they expire. An entry with a JTI blocks that single
+// token; an entry with an empty JTI blocks every token of Subject issued at or before IssuedBefore.
+// ExpiresAt is when the blocked tokens stop being valid anyway, after which the entry can be dropped.
+type TokenRevocation struct {
+	ID           entities.ID `json:"id" gorm:"size:36"`
+	JTI          string      `json:"jti" gorm:"size:64;index"`
+	Subject      string      `json:"subject" gorm:"size:36;index"`
+	IssuedBefore *time.Time  `json:"issued_before"`
+	ExpiresAt    time.Time   `json:"expires_at" gorm:"index"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
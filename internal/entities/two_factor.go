@@ -0,0 +1,160 @@
+package entities
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+	"github.com/caiocp/go-api/pkg/totp"
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes are issued when two-factor authentication is enabled.
+	RecoveryCodeCount = 10
+
+	// MaxTwoFactorAttempts is how many codes may be tried against one login challenge.
+	MaxTwoFactorAttempts = 5
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication has not been set up")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorChallenge      = errors.New("two-factor challenge is invalid, expired or the code is wrong")
+)
+
+// BeginTOTPEnrollment stores a fresh secret that only takes effect once ConfirmTOTP sees a code
+// generated from it. Starting over replaces an unconfirmed secret.
+func (u *User) BeginTOTPEnrollment() (string, error) {
+	if u.TOTPEnabled {
+		return "", ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	u.TOTPSecret = secret
+	return secret, nil
+}
+
+// ConfirmTOTP enables two-factor authentication when code matches the pending secret.
+func (u *User) ConfirmTOTP(code string, now time.Time) error {
+	if u.TOTPEnabled {
+		return ErrTwoFactorAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return ErrTwoFactorNotEnrolled
+	}
+	if !u.CheckTOTP(code, now) {
+		return ErrInvalidTwoFactorCode
+	}
+
+	u.TOTPEnabled = true
+	return nil
+}
+
+// CheckTOTP reports whether code is valid for the secret and newer than the last accepted one, and
+// records its period so the same code cannot be used twice.
+func (u *User) CheckTOTP(code string, now time.Time) bool {
+	if u.TOTPSecret == "" {
+		return false
+	}
+
+	step, ok := totp.Validate(u.TOTPSecret, code, now)
+	if !ok || step <= u.TOTPLastStep {
+		return false
+	}
+
+	u.TOTPLastStep = step
+	return true
+}
+
+func (u *User) DisableTOTP() {
+	u.TOTPEnabled = false
+	u.TOTPSecret = ""
+}
+
+// RecoveryCode stands in for a TOTP code once, for when the authenticator is lost. Only the
+// SHA-256 of the code is stored.
+type RecoveryCode struct {
+	ID        entities.ID `json:"id" gorm:"size:36"`
+	UserID    entities.ID `json:"user_id" gorm:"size:36;index"`
+	CodeHash  string      `json:"-" gorm:"size:64;index"`
+	UsedAt    *time.Time  `json:"used_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewRecoveryCodes returns RecoveryCodeCount codes to store and their plain values, formatted as
+// four groups of four characters, to show the user once.
+func NewRecoveryCodes(userID entities.ID) ([]RecoveryCode, []string, error) {
+	codes := make([]RecoveryCode, RecoveryCodeCount)
+	plains := make([]string, RecoveryCodeCount)
+
+	now := time.Now()
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		encoded := base32.StdEncoding.EncodeToString(raw)
+		plains[i] = strings.Join([]string{encoded[0:4], encoded[4:8], encoded[8:12], encoded[12:16]}, "-")
+
+		codes[i] = RecoveryCode{
+			ID:        entities.NewID(),
+			UserID:    userID,
+			CodeHash:  HashRecoveryCode(plains[i]),
+			CreatedAt: now,
+		}
+	}
+
+	return codes, plains, nil
+}
+
+// HashRecoveryCode ignores case, spaces and dashes so codes can be typed loosely.
+func HashRecoveryCode(plain string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(plain))
+	return hashToken(normalized)
+}
+
+// TwoFactorChallenge is handed out by GetJWT when the password was right but a TOTP or recovery
+// code is still needed. Only the SHA-256 of the token is stored.
+type TwoFactorChallenge struct {
+	ID        entities.ID `json:"id" gorm:"size:36"`
+	UserID    entities.ID `json:"user_id" gorm:"size:36;index"`
+	TokenHash string      `json:"-" gorm:"size:64;uniqueIndex"`
+	Attempts  int         `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	UsedAt    *time.Time  `json:"used_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewTwoFactorChallenge returns the challenge to store and the plain token to hand to the client.
+func NewTwoFactorChallenge(userID entities.ID, ttl time.Duration) (*TwoFactorChallenge, string, error) {
+	plain, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &TwoFactorChallenge{
+		ID:        entities.NewID(),
+		UserID:    userID,
+		TokenHash: HashTwoFactorChallenge(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func HashTwoFactorChallenge(plain string) string {
+	return hashToken(plain)
+}
+
+// IsOpen reports whether the challenge can still be answered.
+func (c *TwoFactorChallenge) IsOpen() bool {
+	return c.UsedAt == nil && c.Attempts < MaxTwoFactorAttempts && time.Now().Before(c.ExpiresAt)
+}
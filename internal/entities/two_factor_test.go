@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/pkg/entities"
+	"github.com/caiocp/go-api/pkg/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPEnrollment(t *testing.T) {
+	user, _ := NewUser("John Doe", "email@example.com", "12345678")
+	now := time.Now()
+
+	assert.Equal(t, ErrTwoFactorNotEnrolled, user.ConfirmTOTP("123456", now))
+
+	secret, err := user.BeginTOTPEnrollment()
+	assert.NoError(t, err)
+	assert.Equal(t, secret, user.TOTPSecret)
+	assert.False(t, user.TOTPEnabled)
+
+	assert.Equal(t, ErrInvalidTwoFactorCode, user.ConfirmTOTP("000000x", now))
+
+	code, _ := totp.Code(secret, totp.Step(now))
+	assert.NoError(t, user.ConfirmTOTP(code, now))
+	assert.True(t, user.TOTPEnabled)
+
+	_, err = user.BeginTOTPEnrollment()
+	assert.Equal(t, ErrTwoFactorAlreadyEnabled, err)
+
+	user.DisableTOTP()
+	assert.False(t, user.TOTPEnabled)
+	assert.Empty(t, user.TOTPSecret)
+}
+
+func TestCheckTOTPRejectsReplay(t *testing.T) {
+	user, _ := NewUser("John Doe", "email@example.com", "12345678")
+	secret, _ := user.BeginTOTPEnrollment()
+	now := time.Now()
+
+	code, _ := totp.Code(secret, totp.Step(now))
+	assert.True(t, user.CheckTOTP(code, now))
+	assert.False(t, user.CheckTOTP(code, now))
+
+	previous, _ := totp.Code(secret, totp.Step(now)-1)
+	assert.False(t, user.CheckTOTP(previous, now))
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, plains, err := NewRecoveryCodes(entities.NewID())
+	assert.NoError(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+	assert.Len(t, plains, RecoveryCodeCount)
+	assert.Regexp(t, `^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`, plains[0])
+	assert.NotEqual(t, plains[0], plains[1])
+
+	assert.Equal(t, codes[0].CodeHash, HashRecoveryCode(plains[0]))
+	loose := plains[0][0:4] + " " + plains[0][5:9] + plains[0][10:]
+	assert.Equal(t, codes[0].CodeHash, HashRecoveryCode(loose))
+}
+
+func TestTwoFactorChallengeIsOpen(t *testing.T) {
+	challenge, plain, err := NewTwoFactorChallenge(entities.NewID(), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, HashTwoFactorChallenge(plain), challenge.TokenHash)
+	assert.True(t, challenge.IsOpen())
+
+	challenge.Attempts = MaxTwoFactorAttempts
+	assert.False(t, challenge.IsOpen())
+
+	expired, _, _ := NewTwoFactorChallenge(entities.NewID(), -time.Second)
+	assert.False(t, expired.IsOpen())
+}
@@ -1,31 +1,125 @@
 package entities
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/caiocp/go-api/pkg/entities"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/caiocp/go-api/pkg/password"
+)
+
+var (
+	ErrIncorrectPassword  = errors.New("current password is incorrect")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrEmailNotVerified   = errors.New("email address has not been verified")
+	ErrPasswordTooShort   = password.ErrTooShort
+	ErrPasswordBreached   = password.ErrBreached
+)
+
+var (
+	passwordHasher password.Hasher = password.DefaultArgon2id
+	passwordPolicy                 = &password.Policy{MinLength: 8}
 )
 
+// ConfigurePasswords sets how new passwords are hashed and which ones are accepted. It is meant to be
+// called once at startup, before any user is created.
+func ConfigurePasswords(hasher password.Hasher, policy *password.Policy) {
+	passwordHasher = hasher
+	passwordPolicy = policy
+}
+
+// HashPassword hashes password with the configured hasher, without checking the password policy.
+func HashPassword(plain string) (string, error) {
+	return passwordHasher.Hash(plain)
+}
+
 type User struct {
-	ID       entities.ID `json:"id"`
+	ID       entities.ID `json:"id" gorm:"size:36"`
 	Name     string      `json:"name"`
-	Email    string      `json:"email"`
+	Email    string      `json:"email" gorm:"size:255;not null;uniqueIndex:idx_users_email"`
 	Password string      `json:"-"`
+	Role     Role        `json:"role" gorm:"size:20;not null;default:viewer"`
+	Verified bool        `json:"verified" gorm:"not null;default:false"`
+
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"column:totp_enabled;not null;default:false"`
+	TOTPSecret   string `json:"-" gorm:"column:totp_secret;size:64"`
+	TOTPLastStep int64  `json:"-" gorm:"column:totp_last_step;not null;default:0"`
 }
 
 func NewUser(name, email, password string) (*User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	user := &User{
+		ID:    entities.NewID(),
+		Name:  name,
+		Email: NormalizeEmail(email),
+		Role:  RoleViewer,
+	}
+
+	if err := user.SetPassword(password); err != nil {
 		return nil, err
 	}
 
-	return &User{
-		ID:       entities.NewID(),
-		Name:     name,
-		Email:    email,
-		Password: string(hash),
-	}, nil
+	return user, nil
+}
+
+// NormalizeEmail trims and lower-cases email so lookups and the unique index treat case variants
+// of an address as the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// SetEmail changes the address and marks it unverified when it differs from the current one.
+func (u *User) SetEmail(email string) {
+	email = NormalizeEmail(email)
+	if email == u.Email {
+		return
+	}
+
+	u.Email = email
+	u.Verified = false
 }
 
-func (u *User) ValidatePassword(password string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+// SetPassword replaces the stored hash with one for plain, which has to satisfy the password policy.
+func (u *User) SetPassword(plain string) error {
+	if err := passwordPolicy.Check(plain); err != nil {
+		return err
+	}
+
+	hash, err := HashPassword(plain)
+	if err != nil {
+		return err
+	}
+
+	u.Password = hash
+	return nil
+}
+
+// ChangePassword sets newPassword only when currentPassword matches the stored hash.
+func (u *User) ChangePassword(currentPassword, newPassword string) error {
+	if !u.ValidatePassword(currentPassword) {
+		return ErrIncorrectPassword
+	}
+
+	return u.SetPassword(newPassword)
+}
+
+func (u *User) ValidatePassword(plain string) bool {
+	return passwordHasher.Verify(u.Password, plain)
+}
+
+// RehashPassword replaces a hash made with an outdated algorithm or parameters by a current one. plain
+// must already have been validated. It reports whether the hash changed and needs saving; the policy
+// is not applied, so a login never fails for a password that was fine when it was chosen.
+func (u *User) RehashPassword(plain string) (bool, error) {
+	if !passwordHasher.NeedsRehash(u.Password) {
+		return false, nil
+	}
+
+	hash, err := HashPassword(plain)
+	if err != nil {
+		return false, err
+	}
+
+	u.Password = hash
+	return true, nil
 }
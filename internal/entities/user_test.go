@@ -1,25 +1,88 @@
 package entities
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestNewUser(t *testing.T) {
-	user, err := NewUser("John Doe", "email@example.com", "123456")
+	user, err := NewUser("John Doe", "email@example.com", "12345678")
 	assert.Nil(t, err)
 	assert.NotNil(t, user)
 	assert.NotEmpty(t, user.ID)
 	assert.NotEmpty(t, user.Password)
 	assert.Equal(t, "John Doe", user.Name)
 	assert.Equal(t, "email@example.com", user.Email)
+	assert.Equal(t, RoleViewer, user.Role)
+}
+
+func TestNewUserNormalizesEmail(t *testing.T) {
+	user, err := NewUser("John Doe", "  Email@Example.COM ", "12345678")
+	assert.Nil(t, err)
+	assert.Equal(t, "email@example.com", user.Email)
 }
 
 func TestUserValidatePassword(t *testing.T) {
-	user, err := NewUser("John Doe", "email@example.com", "123456")
+	user, err := NewUser("John Doe", "email@example.com", "12345678")
 	assert.Nil(t, err)
+	assert.True(t, user.ValidatePassword("12345678"))
+	assert.False(t, user.ValidatePassword("abcdefgh"))
+	assert.NotEqual(t, "12345678", user.Password)
+}
+
+func TestUserChangePassword(t *testing.T) {
+	user, err := NewUser("John Doe", "email@example.com", "12345678")
+	assert.Nil(t, err)
+
+	err = user.ChangePassword("wrong", "abcdefgh")
+	assert.Equal(t, ErrIncorrectPassword, err)
+	assert.True(t, user.ValidatePassword("12345678"))
+
+	err = user.ChangePassword("12345678", "abcdefgh")
+	assert.Nil(t, err)
+	assert.True(t, user.ValidatePassword("abcdefgh"))
+	assert.False(t, user.ValidatePassword("12345678"))
+}
+
+func TestUserSetEmail(t *testing.T) {
+	user, _ := NewUser("John Doe", "email@example.com", "12345678")
+	assert.False(t, user.Verified)
+
+	user.Verified = true
+	user.SetEmail(" EMAIL@example.com")
+	assert.True(t, user.Verified)
+
+	user.SetEmail("other@example.com")
+	assert.Equal(t, "other@example.com", user.Email)
+	assert.False(t, user.Verified)
+}
+
+func TestNewUserEnforcesPasswordPolicy(t *testing.T) {
+	_, err := NewUser("John Doe", "email@example.com", "1234567")
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+
+	user, _ := NewUser("John Doe", "email@example.com", "12345678")
+	err = user.ChangePassword("12345678", "abc")
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+	assert.True(t, user.ValidatePassword("12345678"))
+}
+
+func TestUserRehashPassword(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &User{Password: string(legacy)}
+
 	assert.True(t, user.ValidatePassword("123456"))
-	assert.False(t, user.ValidatePassword("abcdef"))
-	assert.NotEqual(t, "123456", user.Password)
+	changed, err := user.RehashPassword("123456")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(user.Password, "$argon2id$"))
+	assert.True(t, user.ValidatePassword("123456"), "the policy does not apply to existing passwords")
+
+	changed, err = user.RehashPassword("123456")
+	assert.NoError(t, err)
+	assert.False(t, changed)
 }
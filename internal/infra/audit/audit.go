@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is one security-relevant action. Target names what the action applied to, such as
+// "account:caio@caio.com" or "ip:10.0.0.1".
+type Event struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Target string    `json:"target"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Logger records audit events.
+type Logger interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Writer writes each event as one JSON line to W, usually a dedicated file or os.Stdout.
+type Writer struct {
+	W  io.Writer
+	mu sync.Mutex
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{W: w}
+}
+
+func (w *Writer) Record(_ context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err = w.W.Write(append(line, '\n'))
+	return err
+}
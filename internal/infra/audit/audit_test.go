@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterRecordsJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWriter(&buf)
+
+	assert.NoError(t, logger.Record(context.Background(), Event{Action: "login.locked", Target: "account:caio@caio.com"}))
+	assert.NoError(t, logger.Record(context.Background(), Event{Action: "login.unlocked", Target: "ip:10.0.0.1", Detail: "lockout expired"}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var event Event
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "login.unlocked", event.Action)
+	assert.Equal(t, "ip:10.0.0.1", event.Target)
+	assert.Equal(t, "lockout expired", event.Detail)
+	assert.False(t, event.Time.IsZero())
+}
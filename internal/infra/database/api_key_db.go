@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"gorm.io/gorm"
+)
+
+type APIKey struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewAPIKey(db *gorm.DB) *APIKey {
+	return &APIKey{DB: db}
+}
+
+func (k *APIKey) Create(ctx context.Context, key *entities.APIKey) error {
+	ctx, cancel := withTimeout(ctx, k.Timeout)
+	defer cancel()
+
+	return k.DB.WithContext(ctx).Create(key).Error
+}
+
+func (k *APIKey) FindByHash(ctx context.Context, hash string) (*entities.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, k.Timeout)
+	defer cancel()
+
+	var key entities.APIKey
+	if err := k.DB.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// FindByUser lists the keys of a user, newest first, including revoked and expired ones.
+func (k *APIKey) FindByUser(ctx context.Context, userID string) ([]entities.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, k.Timeout)
+	defer cancel()
+
+	keys := []entities.APIKey{}
+	err := k.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error
+
+	return keys, err
+}
+
+// RevokeUser disables every live key of a user.
+func (k *APIKey) RevokeUser(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, k.Timeout)
+	defer cancel()
+
+	return k.DB.WithContext(ctx).Model(&entities.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Revoke disables a live key of a user. It returns gorm.ErrRecordNotFound when the user has no such
+// key or it is already revoked.
+func (k *APIKey) Revoke(ctx context.Context, userID, id string) error {
+	ctx, cancel := withTimeout(ctx, k.Timeout)
+	defer cancel()
+
+	result := k.DB.WithContext(ctx).Model(&entities.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (k *APIKey) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, k.Timeout)
+	defer cancel()
+
+	return k.DB.WithContext(ctx).Model(&entities.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCreateAndFindAPIKey(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		key, plain, err := entities.NewAPIKey(entityPkg.NewID(), "ci", []string{"products:read"}, nil)
+		assert.NoError(t, err)
+
+		keyDB := NewAPIKey(db)
+		assert.NoError(t, keyDB.Create(context.Background(), key))
+
+		found, err := keyDB.FindByHash(context.Background(), entities.HashAPIKey(plain))
+		assert.NoError(t, err)
+		assert.Equal(t, key.ID, found.ID)
+		assert.Equal(t, "products:read", found.Scope)
+		assert.Nil(t, found.LastUsedAt)
+
+		usedAt := time.Now().UTC().Truncate(time.Second)
+		assert.NoError(t, keyDB.TouchLastUsed(context.Background(), key.ID.String(), usedAt))
+		found, _ = keyDB.FindByHash(context.Background(), entities.HashAPIKey(plain))
+		assert.NotNil(t, found.LastUsedAt)
+		assert.True(t, usedAt.Equal(*found.LastUsedAt))
+
+		_, err = keyDB.FindByHash(context.Background(), entities.HashAPIKey("unknown"))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestListAndRevokeAPIKeys(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		userID := entityPkg.NewID()
+		older, _, _ := entities.NewAPIKey(userID, "old", nil, nil)
+		older.CreatedAt = time.Now().Add(-time.Hour)
+		newer, _, _ := entities.NewAPIKey(userID, "new", nil, nil)
+		other, _, _ := entities.NewAPIKey(entityPkg.NewID(), "other", nil, nil)
+
+		keyDB := NewAPIKey(db)
+		for _, key := range []*entities.APIKey{older, newer, other} {
+			assert.NoError(t, keyDB.Create(context.Background(), key))
+		}
+
+		keys, err := keyDB.FindByUser(context.Background(), userID.String())
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+		assert.Equal(t, "new", keys[0].Name)
+
+		err = keyDB.Revoke(context.Background(), userID.String(), other.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		assert.NoError(t, keyDB.Revoke(context.Background(), userID.String(), older.ID.String()))
+		err = keyDB.Revoke(context.Background(), userID.String(), older.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		keys, _ = keyDB.FindByUser(context.Background(), userID.String())
+		assert.NotNil(t, keys[1].RevokedAt)
+		assert.False(t, keys[1].IsActive())
+
+		assert.NoError(t, keyDB.RevokeUser(context.Background(), userID.String()))
+		keys, _ = keyDB.FindByUser(context.Background(), userID.String())
+		assert.False(t, keys[0].IsActive())
+		found, _ := keyDB.FindByHash(context.Background(), other.KeyHash)
+		assert.True(t, found.IsActive())
+	})
+}
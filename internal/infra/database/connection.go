@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+
+	// SQLiteMemory is the database name that opens a private in-memory SQLite database.
+	SQLiteMemory = ":memory:"
+
+	defaultSQLiteFile = "test.db"
+)
+
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+type Config struct {
+	Driver          string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Name            string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// NewConnection opens a GORM connection for the configured driver and applies the pool settings.
+func NewConnection(cfg Config) (*gorm.DB, error) {
+	dialector, err := NewDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	if isSQLiteMemory(cfg) {
+		// every connection to :memory: gets its own empty database, so the pool must hold exactly one
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		return db, nil
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
+	return db, nil
+}
+
+// NewDialector builds the GORM dialector for the configured driver.
+func NewDialector(cfg Config) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", DriverSQLite, "sqlite3":
+		if isSQLiteMemory(cfg) {
+			return sqlite.Open("file::memory:"), nil
+		}
+		if cfg.Name == "" {
+			return sqlite.Open(defaultSQLiteFile), nil
+		}
+		return sqlite.Open(cfg.Name), nil
+	case DriverPostgres, "postgresql":
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name)
+		return postgres.Open(dsn), nil
+	case DriverMySQL:
+		// clientFoundRows makes RowsAffected count matched rows, so saving unchanged values is not
+		// mistaken for a missing record
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC&clientFoundRows=true",
+			cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
+		return mysql.Open(dsn), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedDriver, cfg.Driver)
+}
+
+func isSQLiteMemory(cfg Config) bool {
+	return (cfg.Driver == "" || cfg.Driver == DriverSQLite || cfg.Driver == "sqlite3") && cfg.Name == SQLiteMemory
+}
+
+// withTimeout bounds ctx by the repository's query timeout. A zero timeout leaves ctx untouched.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDialector(t *testing.T) {
+	cases := map[string]string{
+		"":             "sqlite",
+		DriverSQLite:   "sqlite",
+		DriverPostgres: "postgres",
+		DriverMySQL:    "mysql",
+	}
+
+	for driver, name := range cases {
+		dialector, err := NewDialector(Config{Driver: driver, Host: "localhost", Port: "5432", Name: "app"})
+		assert.NoError(t, err)
+		assert.Equal(t, name, dialector.Name())
+	}
+}
+
+func TestNewDialectorWhenDriverIsUnsupported(t *testing.T) {
+	dialector, err := NewDialector(Config{Driver: "oracle"})
+	assert.ErrorIs(t, err, ErrUnsupportedDriver)
+	assert.Nil(t, dialector)
+}
+
+func TestNewConnectionSQLiteMemory(t *testing.T) {
+	db, err := NewConnection(Config{Driver: DriverSQLite, Name: SQLiteMemory})
+	assert.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sqlDB.Stats().MaxOpenConnections)
+}
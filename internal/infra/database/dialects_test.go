@@ -0,0 +1,77 @@
+package database
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+	"gorm.io/gorm"
+)
+
+// testDialects lists the backends the repository tests run against. SQLite in-memory is always
+// available; the others are enabled by setting TEST_<DRIVER>_HOST (plus _PORT, _USER, _PASSWORD
+// and _NAME) to a server that can be freely wiped.
+func testDialects() map[string]Config {
+	dialects := map[string]Config{
+		DriverSQLite: {Driver: DriverSQLite, Name: SQLiteMemory},
+	}
+
+	for _, driver := range []string{DriverPostgres, DriverMySQL} {
+		prefix := "TEST_" + strings.ToUpper(driver) + "_"
+		host := os.Getenv(prefix + "HOST")
+		if host == "" {
+			continue
+		}
+
+		dialects[driver] = Config{
+			Driver:   driver,
+			Host:     host,
+			Port:     os.Getenv(prefix + "PORT"),
+			User:     os.Getenv(prefix + "USER"),
+			Password: os.Getenv(prefix + "PASSWORD"),
+			Name:     os.Getenv(prefix + "NAME"),
+		}
+	}
+
+	return dialects
+}
+
+// forEachDialect runs fn as a subtest against a freshly migrated database for every available dialect.
+func forEachDialect(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	for name, cfg := range testDialects() {
+		cfg := cfg
+		t.Run(name, func(t *testing.T) {
+			db, err := NewConnection(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resetTables(t, db)
+			if _, err := migrations.NewMigrator(db, migrations.All()).Up(); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				resetTables(t, db)
+				if sqlDB, err := db.DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+
+			fn(t, db)
+		})
+	}
+}
+
+func resetTables(t *testing.T, db *gorm.DB) {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range tables {
+		if err := db.Migrator().DropTable(table); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationToken struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewEmailVerificationToken(db *gorm.DB) *EmailVerificationToken {
+	return &EmailVerificationToken{DB: db}
+}
+
+func (t *EmailVerificationToken) Create(ctx context.Context, token *entities.EmailVerificationToken) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Create(token).Error
+}
+
+func (t *EmailVerificationToken) FindByHash(ctx context.Context, hash string) (*entities.EmailVerificationToken, error) {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	var token entities.EmailVerificationToken
+	if err := t.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// FindLatest returns the most recently issued token of a user, which paces resends.
+func (t *EmailVerificationToken) FindLatest(ctx context.Context, userID string) (*entities.EmailVerificationToken, error) {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	var token entities.EmailVerificationToken
+	err := t.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at desc").First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed consumes a token. It returns entities.ErrInvalidVerificationToken when the token was
+// already used.
+func (t *EmailVerificationToken) MarkUsed(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	result := t.DB.WithContext(ctx).Model(&entities.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrInvalidVerificationToken
+	}
+
+	return nil
+}
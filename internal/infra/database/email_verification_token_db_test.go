@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCreateAndFindEmailVerificationToken(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+		token, plain, err := entities.NewEmailVerificationToken(user, time.Hour)
+		assert.NoError(t, err)
+
+		tokenDB := NewEmailVerificationToken(db)
+		err = tokenDB.Create(context.Background(), token)
+		assert.NoError(t, err)
+
+		found, err := tokenDB.FindByHash(context.Background(), entities.HashEmailVerificationToken(plain))
+		assert.NoError(t, err)
+		assert.Equal(t, token.ID, found.ID)
+		assert.Equal(t, "caio@caio.com", found.Email)
+
+		_, err = tokenDB.FindByHash(context.Background(), entities.HashEmailVerificationToken("unknown"))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		err = tokenDB.MarkUsed(context.Background(), token.ID.String())
+		assert.NoError(t, err)
+		err = tokenDB.MarkUsed(context.Background(), token.ID.String())
+		assert.ErrorIs(t, err, entities.ErrInvalidVerificationToken)
+	})
+}
+
+func TestFindLatestEmailVerificationToken(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+		older, _, _ := entities.NewEmailVerificationToken(user, time.Hour)
+		older.CreatedAt = time.Now().Add(-time.Hour)
+		newer, _, _ := entities.NewEmailVerificationToken(user, time.Hour)
+
+		tokenDB := NewEmailVerificationToken(db)
+		assert.NoError(t, tokenDB.Create(context.Background(), older))
+		assert.NoError(t, tokenDB.Create(context.Background(), newer))
+
+		found, err := tokenDB.FindLatest(context.Background(), user.ID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, newer.ID, found.ID)
+
+		_, err = tokenDB.FindLatest(context.Background(), "unknown")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
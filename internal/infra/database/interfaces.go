@@ -1,16 +1,98 @@
 package database
 
-import "github.com/caiocp/go-api/internal/entities"
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/pkg/query"
+)
 
 type UserInterface interface {
-	Create(user *entities.User) error
-	FindByEmail(email string) (*entities.User, error)
+	Create(ctx context.Context, user *entities.User) error
+	FindByID(ctx context.Context, id string) (*entities.User, error)
+	FindByEmail(ctx context.Context, email string) (*entities.User, error)
+	UpdateProfile(ctx context.Context, user *entities.User) error
+	MarkVerified(ctx context.Context, id, email string) error
+	UpdatePassword(ctx context.Context, id, hash string) error
+	UpdateTOTP(ctx context.Context, user *entities.User) error
+	ReplacePasswordHash(ctx context.Context, id, oldHash, newHash string) error
+	UpdateRole(ctx context.Context, id string, role entities.Role) error
+	UpdateTOTPStep(ctx context.Context, id string, step int64) error
+	Delete(ctx context.Context, id string) error
+}
+
+type RefreshTokenInterface interface {
+	Create(ctx context.Context, token *entities.RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (*entities.RefreshToken, error)
+	Revoke(ctx context.Context, id string) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID string) error
+	RevokeClient(ctx context.Context, clientID string) error
+}
+
+type PasswordResetTokenInterface interface {
+	Create(ctx context.Context, token *entities.PasswordResetToken) error
+	FindByHash(ctx context.Context, hash string) (*entities.PasswordResetToken, error)
+	MarkUsed(ctx context.Context, id string) error
+	InvalidateUser(ctx context.Context, userID string) error
+}
+
+type EmailVerificationTokenInterface interface {
+	Create(ctx context.Context, token *entities.EmailVerificationToken) error
+	FindByHash(ctx context.Context, hash string) (*entities.EmailVerificationToken, error)
+	FindLatest(ctx context.Context, userID string) (*entities.EmailVerificationToken, error)
+	MarkUsed(ctx context.Context, id string) error
+}
+
+type RecoveryCodeInterface interface {
+	Replace(ctx context.Context, userID string, codes []entities.RecoveryCode) error
+	Use(ctx context.Context, userID, hash string) error
+}
+
+type TwoFactorChallengeInterface interface {
+	Create(ctx context.Context, challenge *entities.TwoFactorChallenge) error
+	FindByHash(ctx context.Context, hash string) (*entities.TwoFactorChallenge, error)
+	ReserveAttempt(ctx context.Context, id string) error
+	MarkUsed(ctx context.Context, id string) error
+}
+
+type APIKeyInterface interface {
+	Create(ctx context.Context, key *entities.APIKey) error
+	FindByHash(ctx context.Context, hash string) (*entities.APIKey, error)
+	FindByUser(ctx context.Context, userID string) ([]entities.APIKey, error)
+	Revoke(ctx context.Context, userID, id string) error
+	RevokeUser(ctx context.Context, userID string) error
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type OAuthClientInterface interface {
+	Create(ctx context.Context, client *entities.OAuthClient) error
+	FindByID(ctx context.Context, id string) (*entities.OAuthClient, error)
+	FindAll(ctx context.Context) ([]entities.OAuthClient, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type AuthorizationCodeInterface interface {
+	Create(ctx context.Context, code *entities.AuthorizationCode) error
+	FindByHash(ctx context.Context, hash string) (*entities.AuthorizationCode, error)
+	MarkUsed(ctx context.Context, id, familyID, tokenID string) error
 }
 
 type ProductInterface interface {
-	Create(product *entities.Product) error
-	FindAll(page, limit int, sort string) ([]entities.Product, error)
-	FindByID(id string) (*entities.Product, error)
-	Update(product *entities.Product) error
-	Delete(id string) error
+	Create(ctx context.Context, product *entities.Product) error
+	FindAll(ctx context.Context, spec query.Spec) ([]entities.Product, int64, error)
+	FindByID(ctx context.Context, id string) (*entities.Product, error)
+	FindDeleted(ctx context.Context, spec query.Spec) ([]entities.Product, int64, error)
+	FindDeletedByID(ctx context.Context, id string) (*entities.Product, error)
+	Update(ctx context.Context, product *entities.Product) error
+	UpdateOwner(ctx context.Context, id string, version int, ownerID string) error
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string, version int) error
+	Purge(ctx context.Context, id string, version int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+type TransactionManagerInterface interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
 }
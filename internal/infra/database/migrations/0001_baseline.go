@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The baseline captures the products and users tables as AutoMigrate created them. The models are
+// frozen copies so later changes to the entities cannot alter what this migration does.
+type baselineProduct struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	Name      string
+	Price     float64
+	CreatedAt time.Time
+}
+
+func (baselineProduct) TableName() string {
+	return "products"
+}
+
+type baselineUser struct {
+	ID       string `gorm:"size:36;primaryKey"`
+	Name     string
+	Email    string
+	Password string
+}
+
+func (baselineUser) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			// databases created by the old AutoMigrate already have these tables; adopt them as-is
+			for _, model := range []interface{}{&baselineProduct{}, &baselineUser{}} {
+				if tx.Migrator().HasTable(model) {
+					continue
+				}
+				if err := tx.Migrator().CreateTable(model); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&baselineUser{}, &baselineProduct{})
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type userWithRole struct {
+	Role string `gorm:"size:20;not null;default:viewer"`
+}
+
+func (userWithRole) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "add_user_role",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&userWithRole{}, "Role"); err != nil {
+				return err
+			}
+			// accounts created before roles existed could edit every product, keep them able to
+			return tx.Exec("UPDATE users SET role = ?", "editor").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&userWithRole{}, "Role")
+		},
+	})
+}
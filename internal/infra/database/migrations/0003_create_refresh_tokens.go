@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type refreshTokenV3 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	UserID    string `gorm:"size:36;index"`
+	FamilyID  string `gorm:"size:36;index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (refreshTokenV3) TableName() string {
+	return "refresh_tokens"
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "create_refresh_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&refreshTokenV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&refreshTokenV3{})
+		},
+	})
+}
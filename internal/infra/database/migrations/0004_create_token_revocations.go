@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type tokenRevocationV4 struct {
+	ID           string `gorm:"size:36;primaryKey"`
+	JTI          string `gorm:"size:64;index"`
+	Subject      string `gorm:"size:36;index"`
+	IssuedBefore *time.Time
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+func (tokenRevocationV4) TableName() string {
+	return "token_revocations"
+}
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "create_token_revocations",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&tokenRevocationV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&tokenRevocationV4{})
+		},
+	})
+}
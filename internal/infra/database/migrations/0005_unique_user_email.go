@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrDuplicateEmails = errors.New("users share an email address, merge or rename them before migrating")
+
+type userWithUniqueEmail struct {
+	Email string `gorm:"size:255;not null;uniqueIndex:idx_users_email"`
+}
+
+func (userWithUniqueEmail) TableName() string {
+	return "users"
+}
+
+type duplicateEmail struct {
+	Email string
+	Count int
+}
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "unique_user_email",
+		Up: func(tx *gorm.DB) error {
+			// refuse to guess which account wins: list every clash so an operator can resolve it
+			var duplicates []duplicateEmail
+			err := tx.Raw(`SELECT LOWER(TRIM(email)) AS email, COUNT(*) AS count FROM users
+				GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1 ORDER BY email`).Scan(&duplicates).Error
+			if err != nil {
+				return err
+			}
+			if len(duplicates) > 0 {
+				report := make([]string, len(duplicates))
+				for i, d := range duplicates {
+					report[i] = fmt.Sprintf("%s (%d accounts)", d.Email, d.Count)
+				}
+				return fmt.Errorf("%w: %s", ErrDuplicateEmails, strings.Join(report, ", "))
+			}
+
+			if err := tx.Exec("UPDATE users SET email = LOWER(TRIM(email))").Error; err != nil {
+				return err
+			}
+			// MySQL cannot index an unbounded text column; sqlite ignores the size anyway
+			if tx.Dialector.Name() != "sqlite" {
+				if err := tx.Migrator().AlterColumn(&userWithUniqueEmail{}, "Email"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&userWithUniqueEmail{}, "idx_users_email")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&userWithUniqueEmail{}, "idx_users_email")
+		},
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passwordResetTokenV6 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	UserID    string `gorm:"size:36;index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (passwordResetTokenV6) TableName() string {
+	return "password_reset_tokens"
+}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "create_password_reset_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&passwordResetTokenV6{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&passwordResetTokenV6{})
+		},
+	})
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userWithVerified struct {
+	Verified bool `gorm:"not null;default:false"`
+}
+
+func (userWithVerified) TableName() string {
+	return "users"
+}
+
+type emailVerificationTokenV7 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	UserID    string `gorm:"size:36;index"`
+	Email     string `gorm:"size:255"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (emailVerificationTokenV7) TableName() string {
+	return "email_verification_tokens"
+}
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "add_email_verification",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&userWithVerified{}, "Verified"); err != nil {
+				return err
+			}
+			// accounts created before verification existed keep logging in as they did
+			if err := tx.Exec("UPDATE users SET verified = ?", true).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&emailVerificationTokenV7{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&emailVerificationTokenV7{}); err != nil {
+				return err
+			}
+			// gorm rebuilds sqlite tables to drop a column, which loses idx_users_email; every supported
+			// database can drop it in place
+			return tx.Exec("ALTER TABLE users DROP COLUMN verified").Error
+		},
+	})
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userWithTOTP struct {
+	TOTPEnabled  bool   `gorm:"column:totp_enabled;not null;default:false"`
+	TOTPSecret   string `gorm:"column:totp_secret;size:64"`
+	TOTPLastStep int64  `gorm:"column:totp_last_step;not null;default:0"`
+}
+
+func (userWithTOTP) TableName() string {
+	return "users"
+}
+
+type recoveryCodeV8 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	UserID    string `gorm:"size:36;index"`
+	CodeHash  string `gorm:"size:64;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (recoveryCodeV8) TableName() string {
+	return "recovery_codes"
+}
+
+type twoFactorChallengeV8 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	UserID    string `gorm:"size:36;index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	Attempts  int    `gorm:"not null;default:0"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (twoFactorChallengeV8) TableName() string {
+	return "two_factor_challenges"
+}
+
+var totpColumns = []string{"TOTPEnabled", "TOTPSecret", "TOTPLastStep"}
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "add_two_factor",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range totpColumns {
+				if err := tx.Migrator().AddColumn(&userWithTOTP{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&recoveryCodeV8{}, &twoFactorChallengeV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&twoFactorChallengeV8{}, &recoveryCodeV8{}); err != nil {
+				return err
+			}
+			// dropped in place for the same reason as in 0007
+			for _, column := range []string{"totp_enabled", "totp_secret", "totp_last_step"} {
+				if err := tx.Exec("ALTER TABLE users DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKeyV9 struct {
+	ID         string `gorm:"size:36;primaryKey"`
+	UserID     string `gorm:"size:36;index"`
+	Name       string `gorm:"size:100"`
+	Prefix     string `gorm:"size:16"`
+	KeyHash    string `gorm:"size:64;uniqueIndex"`
+	Scope      string `gorm:"size:255"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiKeyV9) TableName() string {
+	return "api_keys"
+}
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&apiKeyV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiKeyV9{})
+		},
+	})
+}
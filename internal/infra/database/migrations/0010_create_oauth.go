@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type refreshTokenWithClient struct {
+	ClientID string `gorm:"size:36"`
+	Scope    string `gorm:"size:255"`
+}
+
+func (refreshTokenWithClient) TableName() string {
+	return "refresh_tokens"
+}
+
+type oauthClientV10 struct {
+	ID           string `gorm:"size:36;primaryKey"`
+	Name         string `gorm:"size:100"`
+	SecretHash   string `gorm:"size:64"`
+	RedirectURIs string `gorm:"size:2048"`
+	GrantTypes   string `gorm:"size:255"`
+	Scope        string `gorm:"size:255"`
+	CreatedAt    time.Time
+}
+
+func (oauthClientV10) TableName() string {
+	return "oauth_clients"
+}
+
+type authorizationCodeV10 struct {
+	ID            string `gorm:"size:36;primaryKey"`
+	CodeHash      string `gorm:"size:64;uniqueIndex"`
+	ClientID      string `gorm:"size:36"`
+	UserID        string `gorm:"size:36"`
+	RedirectURI   string `gorm:"size:2048"`
+	Scope         string `gorm:"size:255"`
+	CodeChallenge string `gorm:"size:128"`
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+}
+
+func (authorizationCodeV10) TableName() string {
+	return "authorization_codes"
+}
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "create_oauth",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"ClientID", "Scope"} {
+				if err := tx.Migrator().AddColumn(&refreshTokenWithClient{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&oauthClientV10{}, &authorizationCodeV10{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&authorizationCodeV10{}, &oauthClientV10{}); err != nil {
+				return err
+			}
+			// dropped in place for the same reason as in 0007
+			for _, column := range []string{"client_id", "scope"} {
+				if err := tx.Exec("ALTER TABLE refresh_tokens DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type productWithOwner struct {
+	OwnerID *string `gorm:"size:36;index"`
+}
+
+func (productWithOwner) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "add_product_owner",
+		Up: func(tx *gorm.DB) error {
+			// products created before ownership existed keep no owner, so only admins can change them
+			if err := tx.Migrator().AddColumn(&productWithOwner{}, "OwnerID"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&productWithOwner{}, "OwnerID")
+		},
+		Down: func(tx *gorm.DB) error {
+			// sqlite cannot drop an indexed column in place
+			if err := tx.Migrator().DropIndex(&productWithOwner{}, "OwnerID"); err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE products DROP COLUMN owner_id").Error
+		},
+	})
+}
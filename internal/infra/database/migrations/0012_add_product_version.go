@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type productWithVersion struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (productWithVersion) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "add_product_version",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&productWithVersion{}, "Version")
+		},
+		Down: func(tx *gorm.DB) error {
+			// dropped in place so sqlite keeps idx_products_owner_id
+			return tx.Exec("ALTER TABLE products DROP COLUMN version").Error
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type productWithDeletedAt struct {
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (productWithDeletedAt) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "add_product_deleted_at",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&productWithDeletedAt{}, "DeletedAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&productWithDeletedAt{}, "DeletedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			// sqlite cannot drop an indexed column in place
+			if err := tx.Migrator().DropIndex(&productWithDeletedAt{}, "DeletedAt"); err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE products DROP COLUMN deleted_at").Error
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type authorizationCodeWithGrant struct {
+	FamilyID *string `gorm:"size:36"`
+	TokenID  string  `gorm:"size:36"`
+}
+
+func (authorizationCodeWithGrant) TableName() string {
+	return "authorization_codes"
+}
+
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "add_authorization_code_grant",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"FamilyID", "TokenID"} {
+				if err := tx.Migrator().AddColumn(&authorizationCodeWithGrant{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range []string{"family_id", "token_id"} {
+				if err := tx.Exec("ALTER TABLE authorization_codes DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMigrationName = errors.New("migration name must contain only letters, digits and underscores")
+
+	migrationFileRegexp = regexp.MustCompile(`^(\d+)_[a-z0-9_]+\.go$`)
+	migrationNameRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+const migrationTemplate = `package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: %d,
+		Name:    %q,
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
+
+// Create writes a new empty migration into dir, numbered after the highest existing one,
+// and returns the path of the file.
+func Create(dir, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !migrationNameRegexp.MatchString(name) {
+		return "", ErrInvalidMigrationName
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var last int64
+	for _, entry := range entries {
+		match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return "", err
+		}
+		if version > last {
+			last = version
+		}
+	}
+
+	version := last + 1
+	path := filepath.Join(dir, fmt.Sprintf("%04d_%s.go", version, name))
+	content := fmt.Sprintf(migrationTemplate, version, name)
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
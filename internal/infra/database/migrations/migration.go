@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSchemaBehind     = errors.New("database schema is behind, run `migrate up`")
+	ErrNoDownMigration  = errors.New("migration has no down step")
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+)
+
+// Migration is a single numbered schema change. Up and Down receive a transaction that is
+// committed together with the schema_migrations bookkeeping row.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+var registry = map[int64]Migration{}
+
+// register adds a migration to the set compiled into the binary. Each numbered file calls it from init.
+func register(m Migration) {
+	if _, ok := registry[m.Version]; ok {
+		panic(fmt.Errorf("%w: %d", ErrDuplicateVersion, m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All returns every registered migration ordered by version.
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+
+	return all
+}
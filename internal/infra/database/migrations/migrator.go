@@ -0,0 +1,164 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{DB: db, Migrations: sorted}
+}
+
+// Up applies every pending migration in version order and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns the ones it reverted.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return reverted, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, ErrNoDownMigration)
+		}
+
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// Status reports every known migration and when it was applied, if at all.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// EnsureCurrent returns ErrSchemaBehind when any known migration has not been applied.
+func (m *Migrator) EnsureCurrent() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), first is %04d_%s",
+			ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+
+	return nil
+}
+
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	if err := m.DB.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var records []schemaMigration
+	if err := m.DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,128 @@
+package migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return db
+}
+
+func TestMigratorUpAppliesBaseline(t *testing.T) {
+	db := newTestDB(t)
+	migrator := NewMigrator(db, All())
+
+	assert.ErrorIs(t, migrator.EnsureCurrent(), ErrSchemaBehind)
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, applied)
+	assert.True(t, db.Migrator().HasTable("products"))
+	assert.True(t, db.Migrator().HasTable("users"))
+	assert.NoError(t, migrator.EnsureCurrent())
+
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestMigratorUpAdoptsExistingTables(t *testing.T) {
+	db := newTestDB(t)
+	assert.NoError(t, db.Migrator().CreateTable(&baselineProduct{}, &baselineUser{}))
+
+	_, err := NewMigrator(db, All()).Up()
+	assert.NoError(t, err)
+}
+
+func TestMigratorDown(t *testing.T) {
+	db := newTestDB(t)
+	migrator := NewMigrator(db, All())
+
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	reverted, err := migrator.Down(len(All()))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(All()))
+	assert.False(t, db.Migrator().HasTable("products"))
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.Nil(t, s.AppliedAt)
+	}
+}
+
+func TestMigratorUpRollsBackFailedMigration(t *testing.T) {
+	db := newTestDB(t)
+	migrator := NewMigrator(db, []Migration{
+		{Version: 1, Name: "ok", Up: func(tx *gorm.DB) error { return nil }},
+		{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error { return errors.New("boom") }},
+	})
+
+	applied, err := migrator.Up()
+	assert.Error(t, err)
+	assert.Len(t, applied, 1)
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestUniqueUserEmailReportsDuplicates(t *testing.T) {
+	db := newTestDB(t)
+	_, err := NewMigrator(db, All()[:4]).Up()
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Exec(`INSERT INTO users (id, name, email, password) VALUES
+		('1', 'a', 'Caio@Caio.com ', 'x'), ('2', 'b', 'caio@caio.com', 'x'), ('3', 'c', 'Other@Caio.com', 'x')`).Error)
+
+	_, err = NewMigrator(db, All()).Up()
+	assert.ErrorIs(t, err, ErrDuplicateEmails)
+	assert.Contains(t, err.Error(), "caio@caio.com (2 accounts)")
+
+	assert.NoError(t, db.Exec("DELETE FROM users WHERE id = '2'").Error)
+	_, err = NewMigrator(db, All()).Up()
+	assert.NoError(t, err)
+
+	var emails []string
+	assert.NoError(t, db.Raw("SELECT email FROM users ORDER BY id").Scan(&emails).Error)
+	assert.Equal(t, []string{"caio@caio.com", "other@caio.com"}, emails)
+	assert.Error(t, db.Exec("INSERT INTO users (id, name, email, password) VALUES ('4', 'd', 'other@caio.com', 'x')").Error)
+}
+
+func TestCreateMigrationFile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0007_existing.go"), []byte("package migrations\n"), 0o644))
+
+	path, err := Create(dir, "Add_Stock")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0008_add_stock.go"), path)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "Version: 8,")
+
+	_, err = Create(dir, "bad name")
+	assert.ErrorIs(t, err, ErrInvalidMigrationName)
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"gorm.io/gorm"
+)
+
+type OAuthClient struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewOAuthClient(db *gorm.DB) *OAuthClient {
+	return &OAuthClient{DB: db}
+}
+
+func (c *OAuthClient) Create(ctx context.Context, client *entities.OAuthClient) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	return c.DB.WithContext(ctx).Create(client).Error
+}
+
+func (c *OAuthClient) FindByID(ctx context.Context, id string) (*entities.OAuthClient, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var client entities.OAuthClient
+	if err := c.DB.WithContext(ctx).Where("id = ?", id).First(&client).Error; err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (c *OAuthClient) FindAll(ctx context.Context) ([]entities.OAuthClient, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	clients := []entities.OAuthClient{}
+	err := c.DB.WithContext(ctx).Order("created_at").Find(&clients).Error
+
+	return clients, err
+}
+
+func (c *OAuthClient) Delete(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result := c.DB.WithContext(ctx).Where("id = ?", id).Delete(&entities.OAuthClient{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+type AuthorizationCode struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewAuthorizationCode(db *gorm.DB) *AuthorizationCode {
+	return &AuthorizationCode{DB: db}
+}
+
+func (c *AuthorizationCode) Create(ctx context.Context, code *entities.AuthorizationCode) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	return c.DB.WithContext(ctx).Create(code).Error
+}
+
+func (c *AuthorizationCode) FindByHash(ctx context.Context, hash string) (*entities.AuthorizationCode, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var code entities.AuthorizationCode
+	if err := c.DB.WithContext(ctx).Where("code_hash = ?", hash).First(&code).Error; err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// MarkUsed consumes a code and records the refresh token family and access token it is exchanged
+// for. It returns entities.ErrInvalidGrant when it was already used, so two concurrent exchanges of
+// the same code cannot both succeed.
+func (c *AuthorizationCode) MarkUsed(ctx context.Context, id, familyID, tokenID string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result := c.DB.WithContext(ctx).Model(&entities.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Updates(map[string]interface{}{"used_at": time.Now(), "family_id": familyID, "token_id": tokenID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrInvalidGrant
+	}
+
+	return nil
+}
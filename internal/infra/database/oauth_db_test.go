@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestOAuthClients(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		client, secret, err := entities.NewOAuthClient("Partner", []string{"https://partner.example.com/cb"},
+			[]string{entities.GrantAuthorizationCode}, nil, true)
+		assert.NoError(t, err)
+
+		clientDB := NewOAuthClient(db)
+		assert.NoError(t, clientDB.Create(context.Background(), client))
+
+		found, err := clientDB.FindByID(context.Background(), client.ID.String())
+		assert.NoError(t, err)
+		assert.True(t, found.Authenticate(secret))
+		assert.True(t, found.AllowsRedirectURI("https://partner.example.com/cb"))
+
+		clients, err := clientDB.FindAll(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, clients, 1)
+
+		assert.NoError(t, clientDB.Delete(context.Background(), client.ID.String()))
+		assert.ErrorIs(t, clientDB.Delete(context.Background(), client.ID.String()), gorm.ErrRecordNotFound)
+		_, err = clientDB.FindByID(context.Background(), client.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestAuthorizationCodes(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		code, plain, _ := entities.NewAuthorizationCode(entityPkg.NewID(), entityPkg.NewID(), "https://partner.example.com/cb",
+			"products:read", entities.PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"), time.Minute)
+
+		codeDB := NewAuthorizationCode(db)
+		assert.NoError(t, codeDB.Create(context.Background(), code))
+
+		found, err := codeDB.FindByHash(context.Background(), entities.HashAuthorizationCode(plain))
+		assert.NoError(t, err)
+		assert.Equal(t, code.ClientID, found.ClientID)
+		assert.True(t, found.VerifyPKCE("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+		familyID, tokenID := entityPkg.NewID(), entityPkg.NewID()
+		assert.NoError(t, codeDB.MarkUsed(context.Background(), code.ID.String(), familyID.String(), tokenID.String()))
+		assert.ErrorIs(t, codeDB.MarkUsed(context.Background(), code.ID.String(), entityPkg.NewID().String(), entityPkg.NewID().String()), entities.ErrInvalidGrant)
+
+		found, _ = codeDB.FindByHash(context.Background(), entities.HashAuthorizationCode(plain))
+		assert.True(t, found.IsUsed())
+		assert.Equal(t, familyID, *found.FamilyID)
+		assert.Equal(t, tokenID.String(), found.TokenID)
+	})
+}
+
+func TestRevokeClientRefreshTokens(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		userID := entityPkg.NewID()
+		clientID := entityPkg.NewID().String()
+
+		delegated, delegatedPlain, _ := entities.NewRefreshToken(userID, entityPkg.NewID(), time.Hour)
+		delegated.ClientID = clientID
+		delegated.Scope = "products:read"
+		firstParty, firstPartyPlain, _ := entities.NewRefreshToken(userID, entityPkg.NewID(), time.Hour)
+
+		tokenDB := NewRefreshToken(db)
+		assert.NoError(t, tokenDB.Create(context.Background(), delegated))
+		assert.NoError(t, tokenDB.Create(context.Background(), firstParty))
+
+		assert.NoError(t, tokenDB.RevokeClient(context.Background(), clientID))
+
+		found, _ := tokenDB.FindByHash(context.Background(), entities.HashRefreshToken(delegatedPlain))
+		assert.True(t, found.IsRevoked())
+		assert.Equal(t, "products:read", found.Scope)
+		found, _ = tokenDB.FindByHash(context.Background(), entities.HashRefreshToken(firstPartyPlain))
+		assert.False(t, found.IsRevoked())
+	})
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"gorm.io/gorm"
+)
+
+type PasswordResetToken struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewPasswordResetToken(db *gorm.DB) *PasswordResetToken {
+	return &PasswordResetToken{DB: db}
+}
+
+func (t *PasswordResetToken) Create(ctx context.Context, token *entities.PasswordResetToken) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Create(token).Error
+}
+
+func (t *PasswordResetToken) FindByHash(ctx context.Context, hash string) (*entities.PasswordResetToken, error) {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	var token entities.PasswordResetToken
+	if err := t.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed consumes a token. It returns entities.ErrInvalidPasswordResetToken when the token was
+// already used, so two concurrent confirmations cannot both succeed.
+func (t *PasswordResetToken) MarkUsed(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	result := t.DB.WithContext(ctx).Model(&entities.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrInvalidPasswordResetToken
+	}
+
+	return nil
+}
+
+// InvalidateUser consumes every outstanding token of a user, so an older email cannot be used once
+// the password has been reset.
+func (t *PasswordResetToken) InvalidateUser(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Model(&entities.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
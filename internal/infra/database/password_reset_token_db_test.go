@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCreateAndFindPasswordResetToken(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		token, plain, err := entities.NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+		assert.NoError(t, err)
+
+		tokenDB := NewPasswordResetToken(db)
+		err = tokenDB.Create(context.Background(), token)
+		assert.NoError(t, err)
+
+		found, err := tokenDB.FindByHash(context.Background(), entities.HashPasswordResetToken(plain))
+		assert.NoError(t, err)
+		assert.Equal(t, token.ID, found.ID)
+		assert.Equal(t, token.UserID, found.UserID)
+		assert.False(t, found.IsUsed())
+
+		_, err = tokenDB.FindByHash(context.Background(), entities.HashPasswordResetToken("unknown"))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestMarkPasswordResetTokenUsed(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		token, plain, _ := entities.NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+
+		tokenDB := NewPasswordResetToken(db)
+		assert.NoError(t, tokenDB.Create(context.Background(), token))
+
+		err := tokenDB.MarkUsed(context.Background(), token.ID.String())
+		assert.NoError(t, err)
+
+		err = tokenDB.MarkUsed(context.Background(), token.ID.String())
+		assert.ErrorIs(t, err, entities.ErrInvalidPasswordResetToken)
+
+		found, err := tokenDB.FindByHash(context.Background(), entities.HashPasswordResetToken(plain))
+		assert.NoError(t, err)
+		assert.True(t, found.IsUsed())
+	})
+}
+
+func TestInvalidateUserPasswordResetTokens(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		userID := entityPkg.NewID()
+		first, _, _ := entities.NewPasswordResetToken(userID, time.Hour)
+		second, _, _ := entities.NewPasswordResetToken(userID, time.Hour)
+		other, _, _ := entities.NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+
+		tokenDB := NewPasswordResetToken(db)
+		for _, token := range []*entities.PasswordResetToken{first, second, other} {
+			assert.NoError(t, tokenDB.Create(context.Background(), token))
+		}
+
+		err := tokenDB.InvalidateUser(context.Background(), userID.String())
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, tokenDB.MarkUsed(context.Background(), first.ID.String()), entities.ErrInvalidPasswordResetToken)
+		assert.ErrorIs(t, tokenDB.MarkUsed(context.Background(), second.ID.String()), entities.ErrInvalidPasswordResetToken)
+		assert.NoError(t, tokenDB.MarkUsed(context.Background(), other.ID.String()))
+	})
+}
@@ -1,60 +1,202 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/pkg/query"
 	"gorm.io/gorm"
 )
 
 type Product struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Timeout time.Duration
 }
 
 func NewProduct(db *gorm.DB) *Product {
 	return &Product{DB: db}
 }
 
-func (p *Product) Create(product *entities.Product) error {
-	return p.DB.Create(product).Error
+func (p *Product) Create(ctx context.Context, product *entities.Product) error {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	return p.DB.WithContext(ctx).Create(product).Error
 }
 
-func (p *Product) FindAll(page, limit int, sort string) ([]entities.Product, error) {
-	var products []entities.Product
-	var err error
+// FindAll lists the products outside the trash that match spec, along with how many match across all
+// pages.
+func (p *Product) FindAll(ctx context.Context, spec query.Spec) ([]entities.Product, int64, error) {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
 
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
-	}
+	db := applyFilters(p.DB.WithContext(ctx).Model(&entities.Product{}).Where("deleted_at IS NULL"), spec.Filters).
+		Session(&gorm.Session{})
 
-	if page != 0 && limit != 0 {
-		err = p.DB.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
-	} else {
-		err = p.DB.Order("created_at " + sort).Find(&products).Error
+	var total int64
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return products, err
+	var products []entities.Product
+	err = applyPage(db, spec).Find(&products).Error
+
+	return products, total, err
 }
 
-func (p *Product) FindByID(id string) (*entities.Product, error) {
+// FindByID finds a product outside the trash.
+func (p *Product) FindByID(ctx context.Context, id string) (*entities.Product, error) {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
 	var product entities.Product
-	err := p.DB.First(&product, "id = ?", id).Error
+	err := p.DB.WithContext(ctx).First(&product, "id = ? AND deleted_at IS NULL", id).Error
 
 	return &product, err
 }
 
-func (p *Product) Update(product *entities.Product) error {
-	_, err := p.FindByID(product.ID.String())
+// FindDeleted lists the products in the trash that match spec, along with how many match across all
+// pages.
+func (p *Product) FindDeleted(ctx context.Context, spec query.Spec) ([]entities.Product, int64, error) {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	db := applyFilters(p.DB.WithContext(ctx).Model(&entities.Product{}).Where("deleted_at IS NOT NULL"), spec.Filters).
+		Session(&gorm.Session{})
+
+	var total int64
+	err := db.Count(&total).Error
 	if err != nil {
-		return err
+		return nil, 0, err
+	}
+
+	var products []entities.Product
+	err = applyPage(db, spec).Find(&products).Error
+
+	return products, total, err
+}
+
+// FindDeletedByID finds a product in the trash.
+func (p *Product) FindDeletedByID(ctx context.Context, id string) (*entities.Product, error) {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	var product entities.Product
+	err := p.DB.WithContext(ctx).First(&product, "id = ? AND deleted_at IS NOT NULL", id).Error
+
+	return &product, err
+}
+
+// Update stores the name and price of product if it is still at product.Version, and moves it to
+// the next version. It returns entities.ErrProductModified when another change got there first.
+func (p *Product) Update(ctx context.Context, product *entities.Product) error {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	result := p.DB.WithContext(ctx).Model(&entities.Product{}).
+		Where("id = ? AND version = ? AND deleted_at IS NULL", product.ID, product.Version).
+		Updates(map[string]interface{}{
+			"name":    product.Name,
+			"price":   product.Price,
+			"version": product.Version + 1,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return p.missingOrModified(ctx, "id = ? AND deleted_at IS NULL", product.ID)
 	}
 
-	return p.DB.Save(product).Error
+	product.Version++
+	return nil
 }
 
-func (p *Product) Delete(id string) error {
-	product, err := p.FindByID(id)
-	if err != nil {
+// UpdateOwner hands the product to ownerID, which counts as a new version. It returns
+// entities.ErrProductModified when the product is no longer at version.
+func (p *Product) UpdateOwner(ctx context.Context, id string, version int, ownerID string) error {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	result := p.DB.WithContext(ctx).Model(&entities.Product{}).
+		Where("id = ? AND version = ? AND deleted_at IS NULL", id, version).
+		Updates(map[string]interface{}{"owner_id": ownerID, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return p.missingOrModified(ctx, "id = ? AND deleted_at IS NULL", id)
+	}
+
+	return nil
+}
+
+// Delete moves a product to the trash. It returns entities.ErrProductModified when the product is no
+// longer at version.
+func (p *Product) Delete(ctx context.Context, id string, version int) error {
+	return p.setDeletedAt(ctx, id, version, "deleted_at IS NULL", time.Now())
+}
+
+// Restore takes a product out of the trash. It returns entities.ErrProductModified when the product
+// is no longer at version.
+func (p *Product) Restore(ctx context.Context, id string, version int) error {
+	return p.setDeletedAt(ctx, id, version, "deleted_at IS NOT NULL", nil)
+}
+
+// setDeletedAt moves a product in or out of the trash, which counts as a new version.
+func (p *Product) setDeletedAt(ctx context.Context, id string, version int, state string, deletedAt interface{}) error {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	result := p.DB.WithContext(ctx).Model(&entities.Product{}).Where("id = ? AND version = ? AND "+state, id, version).
+		Updates(map[string]interface{}{"deleted_at": deletedAt, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return p.missingOrModified(ctx, "id = ? AND "+state, id)
+	}
+
+	return nil
+}
+
+// Purge removes a product for good, whether or not it is in the trash. It returns
+// entities.ErrProductModified when the product is no longer at version.
+func (p *Product) Purge(ctx context.Context, id string, version int) error {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	result := p.DB.WithContext(ctx).Delete(&entities.Product{}, "id = ? AND version = ?", id, version)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return p.missingOrModified(ctx, "id = ?", id)
+	}
+
+	return nil
+}
+
+// missingOrModified explains why a compare-and-swap on a product matched no row: gorm.ErrRecordNotFound
+// when no product matches where, entities.ErrProductModified when one does at another version.
+func (p *Product) missingOrModified(ctx context.Context, where string, args ...interface{}) error {
+	var count int64
+	if err := p.DB.WithContext(ctx).Model(&entities.Product{}).Where(where, args...).Count(&count).Error; err != nil {
 		return err
 	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return entities.ErrProductModified
+}
+
+// PurgeDeleted removes the products that went to the trash before before and reports how many.
+func (p *Product) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
 
-	return p.DB.Delete(product).Error
+	result := p.DB.WithContext(ctx).Delete(&entities.Product{}, "deleted_at < ?", before)
+	return result.RowsAffected, result.Error
 }
@@ -1,135 +1,454 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/caiocp/go-api/pkg/query"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func TestCreateNewProduct(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
-	if err != nil {
-		t.Error(err)
-	}
-
-	db.AutoMigrate(&entities.Product{})
-
-	product, err := entities.NewProduct("Product 1", 10.0)
-	assert.NoError(t, err)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
 
-	productDB := NewProduct(db)
+		productDB := NewProduct(db)
 
-	err = productDB.Create(product)
-	assert.NoError(t, err)
-	assert.NotEmpty(t, product.ID)
+		err = productDB.Create(context.Background(), product)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, product.ID)
+	})
 }
 
 func TestFindAllProducts(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
-	if err != nil {
-		t.Error(err)
-	}
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		for i := 0; i < 13; i++ {
+			product, err := entities.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100.0)
+			assert.NoError(t, err)
 
-	db.AutoMigrate(&entities.Product{})
+			db.Create(product)
+		}
 
-	for i := 0; i < 13; i++ {
-		product, err := entities.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100.0)
+		productDB := NewProduct(db)
+		byCreation := []query.Order{{Field: "created_at"}}
+		products, total, err := productDB.FindAll(context.Background(), query.Spec{Page: 1, Limit: 5, Sort: byCreation})
 		assert.NoError(t, err)
+		assert.Equal(t, int64(13), total)
+		assert.Len(t, products, 5)
+		assert.Equal(t, "Product 0", products[0].Name)
+		assert.Equal(t, "Product 4", products[4].Name)
 
-		db.Create(product)
-	}
+		products, _, err = productDB.FindAll(context.Background(), query.Spec{Page: 2, Limit: 5, Sort: byCreation})
+		assert.NoError(t, err)
+		assert.Len(t, products, 5)
+		assert.Equal(t, "Product 5", products[0].Name)
+		assert.Equal(t, "Product 9", products[4].Name)
 
-	productDB := NewProduct(db)
-	products, err := productDB.FindAll(1, 5, "asc")
-	assert.NoError(t, err)
-	assert.Len(t, products, 5)
-	assert.Equal(t, "Product 0", products[0].Name)
-	assert.Equal(t, "Product 4", products[4].Name)
+		products, total, err = productDB.FindAll(context.Background(), query.Spec{Page: 3, Limit: 5, Sort: byCreation})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(13), total)
+		assert.Len(t, products, 3)
+		assert.Equal(t, "Product 10", products[0].Name)
+		assert.Equal(t, "Product 12", products[2].Name)
+	})
+}
 
-	products, err = productDB.FindAll(2, 5, "asc")
-	assert.NoError(t, err)
-	assert.Len(t, products, 5)
-	assert.Equal(t, "Product 5", products[0].Name)
-	assert.Equal(t, "Product 9", products[4].Name)
+func TestFindAllProductsOfOwner(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		owner, other := entityPkg.NewID(), entityPkg.NewID()
+		for i, ownerID := range []*entityPkg.ID{&owner, &other, &owner, nil} {
+			product, err := entities.NewProduct(fmt.Sprintf("Product %d", i), 10.0)
+			assert.NoError(t, err)
+			product.OwnerID = ownerID
+
+			db.Create(product)
+		}
+
+		productDB := NewProduct(db)
+		byCreation := []query.Order{{Field: "created_at"}}
+		products, total, err := productDB.FindAll(context.Background(), query.Spec{
+			Filters: []query.Filter{{Field: "owner_id", Op: query.Eq, Value: owner.String()}},
+			Sort:    byCreation,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, products, 2)
+		assert.Equal(t, "Product 0", products[0].Name)
+		assert.Equal(t, "Product 2", products[1].Name)
 
-	products, err = productDB.FindAll(3, 5, "asc")
-	assert.NoError(t, err)
-	assert.Len(t, products, 3)
-	assert.Equal(t, "Product 10", products[0].Name)
-	assert.Equal(t, "Product 12", products[2].Name)
+		products, _, err = productDB.FindAll(context.Background(), query.Spec{Sort: byCreation})
+		assert.NoError(t, err)
+		assert.Len(t, products, 4)
+		assert.Nil(t, products[3].OwnerID)
+	})
+}
+
+func TestFindAllProductsFilteredAndSorted(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		for i, p := range []struct {
+			name  string
+			price float64
+		}{{"Oak chair", 40}, {"Oak table", 120}, {"Pine chair", 25}, {"Chair 100%", 60}, {"Armchair", 60}} {
+			product, err := entities.NewProduct(p.name, p.price)
+			assert.NoError(t, err)
+			product.CreatedAt = created.AddDate(0, 0, i)
+
+			db.Create(product)
+		}
+
+		productDB := NewProduct(db)
+		names := func(spec query.Spec) []string {
+			products, total, err := productDB.FindAll(context.Background(), spec)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(products)), total)
+
+			names := make([]string, len(products))
+			for i, p := range products {
+				names[i] = p.Name
+			}
+			return names
+		}
+		byName := []query.Order{{Field: "name"}}
+
+		assert.Equal(t, []string{"Armchair", "Chair 100%", "Oak chair", "Pine chair"}, names(query.Spec{
+			Filters: []query.Filter{{Field: "name", Op: query.Contains, Value: "CHAIR"}},
+			Sort:    byName,
+		}))
+		assert.Equal(t, []string{"Oak chair", "Oak table"}, names(query.Spec{
+			Filters: []query.Filter{{Field: "name", Op: query.Prefix, Value: "oak"}},
+			Sort:    byName,
+		}))
+		assert.Equal(t, []string{"Chair 100%"}, names(query.Spec{
+			Filters: []query.Filter{{Field: "name", Op: query.Contains, Value: "0%"}},
+		}))
+		assert.Equal(t, []string{"Oak chair", "Chair 100%", "Armchair"}, names(query.Spec{
+			Filters: []query.Filter{
+				{Field: "price", Op: query.Gte, Value: 40.0},
+				{Field: "price", Op: query.Lte, Value: 60.0},
+			},
+			Sort: []query.Order{{Field: "price"}, {Field: "name", Desc: true}},
+		}))
+		assert.Equal(t, []string{"Oak table", "Pine chair"}, names(query.Spec{
+			Filters: []query.Filter{
+				{Field: "created_at", Op: query.Gte, Value: created.AddDate(0, 0, 1)},
+				{Field: "created_at", Op: query.Lt, Value: created.AddDate(0, 0, 3)},
+			},
+			Sort: byName,
+		}))
+	})
 }
 
 func TestFindProductByID(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
-	if err != nil {
-		t.Error(err)
-	}
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
 
-	db.AutoMigrate(&entities.Product{})
+		db.Create(product)
 
-	product, err := entities.NewProduct("Product 1", 10.0)
-	assert.NoError(t, err)
+		productDB := NewProduct(db)
+		product, err = productDB.FindByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, "Product 1", product.Name)
+		assert.Equal(t, 10.0, product.Price)
+	})
+}
 
-	db.Create(product)
+func TestUpdateProduct(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
 
-	productDB := NewProduct(db)
-	product, err = productDB.FindByID(product.ID.String())
-	assert.NoError(t, err)
-	assert.Equal(t, "Product 1", product.Name)
-	assert.Equal(t, 10.0, product.Price)
+		db.Create(product)
+
+		productDB := NewProduct(db)
+		product, err = productDB.FindByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+
+		product.Name = "Product 2"
+		product.Price = 20.0
+
+		err = productDB.Update(context.Background(), product)
+		assert.NoError(t, err)
+
+		product, err = productDB.FindByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, "Product 2", product.Name)
+		assert.Equal(t, 20.0, product.Price)
+		assert.Equal(t, 2, product.Version)
+	})
 }
 
-func TestUpdateProduct(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
-	if err != nil {
-		t.Error(err)
-	}
+func TestUpdateProductDetectsConcurrentChanges(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
 
-	db.AutoMigrate(&entities.Product{})
+		db.Create(product)
 
-	product, err := entities.NewProduct("Product 1", 10.0)
-	assert.NoError(t, err)
+		productDB := NewProduct(db)
+		first, _ := productDB.FindByID(context.Background(), product.ID.String())
+		second, _ := productDB.FindByID(context.Background(), product.ID.String())
 
-	db.Create(product)
+		first.Name = "First"
+		assert.NoError(t, productDB.Update(context.Background(), first))
+		assert.Equal(t, 2, first.Version)
 
-	productDB := NewProduct(db)
-	product, err = productDB.FindByID(product.ID.String())
-	assert.NoError(t, err)
+		second.Name = "Second"
+		err = productDB.Update(context.Background(), second)
+		assert.ErrorIs(t, err, entities.ErrProductModified)
+		assert.Equal(t, 1, second.Version)
 
-	product.Name = "Product 2"
-	product.Price = 20.0
+		product, _ = productDB.FindByID(context.Background(), product.ID.String())
+		assert.Equal(t, "First", product.Name)
 
-	err = productDB.Update(product)
-	assert.NoError(t, err)
+		missing, _ := entities.NewProduct("Missing", 10.0)
+		err = productDB.Update(context.Background(), missing)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
 
-	product, err = productDB.FindByID(product.ID.String())
-	assert.NoError(t, err)
-	assert.Equal(t, "Product 2", product.Name)
-	assert.Equal(t, 20.0, product.Price)
+func TestUpdateProductOwner(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
+
+		db.Create(product)
+
+		productDB := NewProduct(db)
+		owner := entityPkg.NewID()
+		err = productDB.UpdateOwner(context.Background(), product.ID.String(), 1, owner.String())
+		assert.NoError(t, err)
+
+		product, err = productDB.FindByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+		assert.True(t, product.IsOwnedBy(owner.String()))
+		assert.Equal(t, 2, product.Version)
+
+		err = productDB.UpdateOwner(context.Background(), product.ID.String(), 1, entityPkg.NewID().String())
+		assert.ErrorIs(t, err, entities.ErrProductModified)
+
+		err = productDB.UpdateOwner(context.Background(), entityPkg.NewID().String(), 1, owner.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
 }
 
 func TestDeleteProduct(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
+
+		db.Create(product)
+
+		productDB := NewProduct(db)
+		err = productDB.Delete(context.Background(), product.ID.String(), 1)
+		assert.NoError(t, err)
+
+		_, err = productDB.FindByID(context.Background(), product.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		products, _, err := productDB.FindAll(context.Background(), query.Spec{})
+		assert.NoError(t, err)
+		assert.Empty(t, products)
+
+		deleted, err := productDB.FindDeletedByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+		assert.True(t, deleted.IsDeleted())
+		assert.Equal(t, 2, deleted.Version)
+
+		err = productDB.Delete(context.Background(), product.ID.String(), 2)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestDeleteProductDetectsConcurrentChanges(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
+
+		db.Create(product)
+
+		productDB := NewProduct(db)
+		first, _ := productDB.FindByID(context.Background(), product.ID.String())
+		second, _ := productDB.FindByID(context.Background(), product.ID.String())
+
+		first.Name = "First"
+		assert.NoError(t, productDB.Update(context.Background(), first))
+
+		err = productDB.Delete(context.Background(), second.ID.String(), second.Version)
+		assert.ErrorIs(t, err, entities.ErrProductModified)
+
+		product, err = productDB.FindByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, "First", product.Name)
+		assert.False(t, product.IsDeleted())
+	})
+}
+
+func TestFindDeletedProducts(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		productDB := NewProduct(db)
+		owner := entityPkg.NewID()
+		for i := 1; i <= 3; i++ {
+			product, err := entities.NewProduct(fmt.Sprintf("Product %d", i), 10.0)
+			assert.NoError(t, err)
+			if i != 2 {
+				product.OwnerID = &owner
+			}
+			db.Create(product)
+			if i != 3 {
+				assert.NoError(t, productDB.Delete(context.Background(), product.ID.String(), 1))
+			}
+		}
+
+		newest := query.Order{Field: "deleted_at", Desc: true}
+		products, total, err := productDB.FindDeleted(context.Background(), query.Spec{Page: 1, Limit: 1, Sort: []query.Order{newest}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, products, 1)
+
+		ownedBy := query.Filter{Field: "owner_id", Op: query.Eq, Value: owner.String()}
+		products, total, err = productDB.FindDeleted(context.Background(), query.Spec{Filters: []query.Filter{ownedBy}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, products, 1)
+		assert.Equal(t, "Product 1", products[0].Name)
+	})
+}
+
+func TestRestoreProduct(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
+
+		db.Create(product)
+
+		productDB := NewProduct(db)
+		err = productDB.Restore(context.Background(), product.ID.String(), 1)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		assert.NoError(t, productDB.Delete(context.Background(), product.ID.String(), 1))
+		err = productDB.Restore(context.Background(), product.ID.String(), 1)
+		assert.ErrorIs(t, err, entities.ErrProductModified)
+		err = productDB.Restore(context.Background(), product.ID.String(), 2)
+		assert.NoError(t, err)
+
+		product, err = productDB.FindByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+		assert.False(t, product.IsDeleted())
+		assert.Equal(t, 3, product.Version)
+	})
+}
+
+func TestPurgeProduct(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, err := entities.NewProduct("Product 1", 10.0)
+		assert.NoError(t, err)
+
+		db.Create(product)
+
+		productDB := NewProduct(db)
+		err = productDB.Purge(context.Background(), product.ID.String(), 2)
+		assert.ErrorIs(t, err, entities.ErrProductModified)
+
+		err = productDB.Purge(context.Background(), product.ID.String(), 1)
+		assert.NoError(t, err)
+
+		_, err = productDB.FindDeletedByID(context.Background(), product.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		err = productDB.Purge(context.Background(), product.ID.String(), 1)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestPurgeDeletedProducts(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		productDB := NewProduct(db)
+		var ids []string
+		for i := 1; i <= 3; i++ {
+			product, err := entities.NewProduct(fmt.Sprintf("Product %d", i), 10.0)
+			assert.NoError(t, err)
+			db.Create(product)
+			ids = append(ids, product.ID.String())
+		}
+		assert.NoError(t, productDB.Delete(context.Background(), ids[0], 1))
+		assert.NoError(t, productDB.Delete(context.Background(), ids[1], 1))
+		db.Model(&entities.Product{}).Where("id = ?", ids[0]).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+		purged, err := productDB.PurgeDeleted(context.Background(), time.Now().Add(-24*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		_, err = productDB.FindDeletedByID(context.Background(), ids[0])
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = productDB.FindDeletedByID(context.Background(), ids[1])
+		assert.NoError(t, err)
+		_, err = productDB.FindByID(context.Background(), ids[2])
+		assert.NoError(t, err)
+	})
+}
+
+// slowQueries makes every query on db first run a statement that never finishes on its own,
+// so only context cancellation can end it.
+func slowQueries(t *testing.T, db *gorm.DB) {
+	err := db.Callback().Query().Before("gorm:query").Register("test:slow", func(tx *gorm.DB) {
+		rows, err := tx.Statement.ConnPool.QueryContext(tx.Statement.Context,
+			"WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT max(x) FROM c")
+		if err != nil {
+			tx.AddError(err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		tx.AddError(rows.Err())
+	})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+}
 
-	db.AutoMigrate(&entities.Product{})
-
-	product, err := entities.NewProduct("Product 1", 10.0)
+func TestFindAllProductsWhenContextIsCancelled(t *testing.T) {
+	db, err := NewConnection(Config{Driver: DriverSQLite, Name: SQLiteMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrations.NewMigrator(db, migrations.All()).Up()
 	assert.NoError(t, err)
+	slowQueries(t, db)
 
-	db.Create(product)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
 
-	productDB := NewProduct(db)
-	err = productDB.Delete(product.ID.String())
+	start := time.Now()
+	_, _, err = NewProduct(db).FindAll(ctx, query.Spec{})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestFindProductByIDWhenQueryTimesOut(t *testing.T) {
+	db, err := NewConnection(Config{Driver: DriverSQLite, Name: SQLiteMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrations.NewMigrator(db, migrations.All()).Up()
 	assert.NoError(t, err)
+	slowQueries(t, db)
+
+	productDB := NewProduct(db)
+	productDB.Timeout = 50 * time.Millisecond
 
-	_, err = productDB.FindByID(product.ID.String())
+	start := time.Now()
+	_, err = productDB.FindByID(context.Background(), entityPkg.NewID().String())
 	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
 }
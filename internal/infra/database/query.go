@@ -0,0 +1,53 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/caiocp/go-api/pkg/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// likeEscaper escapes the LIKE wildcards in user input; ! is the escape character because a
+// backslash means something else to MySQL string literals.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// applyFilters adds the filters of a query spec to db. Field names come from a query.Builder
+// whitelist, so they are safe to put into the statement; values are always bound.
+func applyFilters(db *gorm.DB, filters []query.Filter) *gorm.DB {
+	for _, f := range filters {
+		switch f.Op {
+		case query.Contains, query.Prefix:
+			pattern := likeEscaper.Replace(strings.ToLower(f.Value.(string))) + "%"
+			if f.Op == query.Contains {
+				pattern = "%" + pattern
+			}
+			db = db.Where("LOWER("+f.Field+") LIKE ? ESCAPE '!'", pattern)
+		case query.Gte:
+			db = db.Where(f.Field+" >= ?", f.Value)
+		case query.Lte:
+			db = db.Where(f.Field+" <= ?", f.Value)
+		case query.Lt:
+			db = db.Where(f.Field+" < ?", f.Value)
+		default:
+			db = db.Where(f.Field+" = ?", f.Value)
+		}
+	}
+
+	return db
+}
+
+// applyPage orders db by the sort of a query spec, then by id so that rows comparing equal keep
+// their place from one page to the next, and restricts it to the requested page.
+func applyPage(db *gorm.DB, spec query.Spec) *gorm.DB {
+	for _, o := range spec.Sort {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: o.Field}, Desc: o.Desc})
+	}
+	db = db.Order("id")
+
+	if spec.Limit > 0 {
+		db = db.Limit(spec.Limit).Offset(spec.Offset())
+	}
+
+	return db
+}
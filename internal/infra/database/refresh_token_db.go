@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"gorm.io/gorm"
+)
+
+type RefreshToken struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewRefreshToken(db *gorm.DB) *RefreshToken {
+	return &RefreshToken{DB: db}
+}
+
+func (t *RefreshToken) Create(ctx context.Context, token *entities.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Create(token).Error
+}
+
+func (t *RefreshToken) FindByHash(ctx context.Context, hash string) (*entities.RefreshToken, error) {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	var token entities.RefreshToken
+	if err := t.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Revoke marks a live token as used. It returns entities.ErrRefreshTokenReused when the token was
+// already revoked, which is how two concurrent rotations of the same token are told apart.
+func (t *RefreshToken) Revoke(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	result := t.DB.WithContext(ctx).Model(&entities.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+func (t *RefreshToken) RevokeFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Model(&entities.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (t *RefreshToken) RevokeUser(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Model(&entities.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (t *RefreshToken) RevokeClient(ctx context.Context, clientID string) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Model(&entities.RefreshToken{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCreateAndFindRefreshToken(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		token, plain, err := entities.NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), time.Hour)
+		assert.NoError(t, err)
+
+		tokenDB := NewRefreshToken(db)
+		err = tokenDB.Create(context.Background(), token)
+		assert.NoError(t, err)
+
+		found, err := tokenDB.FindByHash(context.Background(), entities.HashRefreshToken(plain))
+		assert.NoError(t, err)
+		assert.Equal(t, token.ID, found.ID)
+		assert.Equal(t, token.FamilyID, found.FamilyID)
+		assert.False(t, found.IsRevoked())
+
+		_, err = tokenDB.FindByHash(context.Background(), entities.HashRefreshToken("unknown"))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		token, plain, _ := entities.NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), time.Hour)
+
+		tokenDB := NewRefreshToken(db)
+		assert.NoError(t, tokenDB.Create(context.Background(), token))
+
+		err := tokenDB.Revoke(context.Background(), token.ID.String())
+		assert.NoError(t, err)
+
+		err = tokenDB.Revoke(context.Background(), token.ID.String())
+		assert.ErrorIs(t, err, entities.ErrRefreshTokenReused)
+
+		found, err := tokenDB.FindByHash(context.Background(), entities.HashRefreshToken(plain))
+		assert.NoError(t, err)
+		assert.True(t, found.IsRevoked())
+	})
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		userID, familyID := entityPkg.NewID(), entityPkg.NewID()
+		first, firstPlain, _ := entities.NewRefreshToken(userID, familyID, time.Hour)
+		second, secondPlain, _ := entities.NewRefreshToken(userID, familyID, time.Hour)
+		other, otherPlain, _ := entities.NewRefreshToken(userID, entityPkg.NewID(), time.Hour)
+
+		tokenDB := NewRefreshToken(db)
+		for _, token := range []*entities.RefreshToken{first, second, other} {
+			assert.NoError(t, tokenDB.Create(context.Background(), token))
+		}
+
+		err := tokenDB.RevokeFamily(context.Background(), familyID.String())
+		assert.NoError(t, err)
+
+		for plain, revoked := range map[string]bool{firstPlain: true, secondPlain: true, otherPlain: false} {
+			found, err := tokenDB.FindByHash(context.Background(), entities.HashRefreshToken(plain))
+			assert.NoError(t, err)
+			assert.Equal(t, revoked, found.IsRevoked())
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"gorm.io/gorm"
+)
+
+type TokenRevocation struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewTokenRevocation(db *gorm.DB) *TokenRevocation {
+	return &TokenRevocation{DB: db}
+}
+
+func (t *TokenRevocation) RevokeToken(ctx context.Context, jti, subject string, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Create(&entities.TokenRevocation{
+		ID:        entityPkg.NewID(),
+		JTI:       jti,
+		Subject:   subject,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+func (t *TokenRevocation) RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Create(&entities.TokenRevocation{
+		ID:           entityPkg.NewID(),
+		Subject:      subject,
+		IssuedBefore: &issuedBefore,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    time.Now(),
+	}).Error
+}
+
+func (t *TokenRevocation) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	db := t.DB.WithContext(ctx).Model(&entities.TokenRevocation{}).Where("expires_at > ?", time.Now())
+	match := t.DB.Where("jti = '' AND subject = ? AND issued_before >= ?", subject, issuedAt)
+	if jti != "" {
+		match = match.Or("jti = ?", jti)
+	}
+
+	var count int64
+	err := db.Where(match).Count(&count).Error
+
+	return count > 0, err
+}
+
+func (t *TokenRevocation) DeleteExpired(ctx context.Context, now time.Time) error {
+	ctx, cancel := withTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	return t.DB.WithContext(ctx).Where("expires_at <= ?", now).Delete(&entities.TokenRevocation{}).Error
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRevokeAccessToken(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		now := time.Now()
+		revocationDB := NewTokenRevocation(db)
+
+		err := revocationDB.RevokeToken(ctx, "jti-1", "user", now.Add(time.Minute))
+		assert.NoError(t, err)
+
+		revoked, err := revocationDB.IsRevoked(ctx, "jti-1", "user", now)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = revocationDB.IsRevoked(ctx, "jti-2", "user", now)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+}
+
+func TestRevokeAccessTokensOfSubject(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		now := time.Now()
+		revocationDB := NewTokenRevocation(db)
+
+		err := revocationDB.RevokeSubject(ctx, "user", now, now.Add(time.Minute))
+		assert.NoError(t, err)
+
+		revoked, err := revocationDB.IsRevoked(ctx, "old", "user", now.Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = revocationDB.IsRevoked(ctx, "new", "user", now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+
+		revoked, err = revocationDB.IsRevoked(ctx, "", "other", now.Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+}
+
+func TestDeleteExpiredRevocations(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		now := time.Now()
+		revocationDB := NewTokenRevocation(db)
+
+		assert.NoError(t, revocationDB.RevokeToken(ctx, "expired", "user", now.Add(-time.Minute)))
+		assert.NoError(t, revocationDB.RevokeToken(ctx, "live", "user", now.Add(time.Minute)))
+
+		err := revocationDB.DeleteExpired(ctx, now)
+		assert.NoError(t, err)
+
+		var count int64
+		db.Model(&entities.TokenRevocation{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+}
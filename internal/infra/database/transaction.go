@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// Repositories groups the repositories bound to a single transaction.
+type Repositories struct {
+	Products            ProductInterface
+	Users               UserInterface
+	RecoveryCodes       RecoveryCodeInterface
+	PasswordResetTokens PasswordResetTokenInterface
+}
+
+type TransactionManager struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewTransactionManager(db *gorm.DB) *TransactionManager {
+	return &TransactionManager{DB: db}
+}
+
+// WithinTransaction runs fn with repositories bound to one transaction. It commits when fn returns
+// nil and rolls back when fn returns an error or panics; the panic is re-raised after the rollback.
+// Calling it again with the context handed to fn opens a savepoint inside the outer transaction,
+// so an inner failure only undoes the inner work.
+func (m *TransactionManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	db := m.DB
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		db = tx
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := context.WithValue(ctx, txKey{}, tx)
+
+		return fn(ctx, Repositories{
+			Products:            &Product{DB: tx, Timeout: m.Timeout},
+			Users:               &User{DB: tx, Timeout: m.Timeout},
+			RecoveryCodes:       &RecoveryCode{DB: tx, Timeout: m.Timeout},
+			PasswordResetTokens: &PasswordResetToken{DB: tx, Timeout: m.Timeout},
+		})
+	})
+}
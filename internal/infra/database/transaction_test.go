@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var errAbort = errors.New("abort")
+
+func TestWithinTransactionCommits(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, _ := entities.NewProduct("Product 1", 10.0)
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+
+		err := NewTransactionManager(db).WithinTransaction(context.Background(), func(ctx context.Context, repos Repositories) error {
+			if err := repos.Products.Create(ctx, product); err != nil {
+				return err
+			}
+			return repos.Users.Create(ctx, user)
+		})
+		assert.NoError(t, err)
+
+		_, err = NewProduct(db).FindByID(context.Background(), product.ID.String())
+		assert.NoError(t, err)
+		_, err = NewUser(db).FindByEmail(context.Background(), "caio@caio.com")
+		assert.NoError(t, err)
+	})
+}
+
+func TestWithinTransactionRollsBackOnError(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, _ := entities.NewProduct("Product 1", 10.0)
+
+		err := NewTransactionManager(db).WithinTransaction(context.Background(), func(ctx context.Context, repos Repositories) error {
+			if err := repos.Products.Create(ctx, product); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = NewProduct(db).FindByID(context.Background(), product.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestWithinTransactionRollsBackRecoveryCodes(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+		assert.NoError(t, NewUser(db).Create(context.Background(), user))
+		codes, plains, _ := entities.NewRecoveryCodes(user.ID)
+
+		err := NewTransactionManager(db).WithinTransaction(context.Background(), func(ctx context.Context, repos Repositories) error {
+			if err := repos.RecoveryCodes.Replace(ctx, user.ID.String(), codes); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		err = NewRecoveryCode(db).Use(context.Background(), user.ID.String(), entities.HashRecoveryCode(plains[0]))
+		assert.ErrorIs(t, err, entities.ErrInvalidTwoFactorCode)
+	})
+}
+
+func TestWithinTransactionRollsBackPasswordResetTokens(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		token, plain, _ := entities.NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+		assert.NoError(t, NewPasswordResetToken(db).Create(context.Background(), token))
+
+		err := NewTransactionManager(db).WithinTransaction(context.Background(), func(ctx context.Context, repos Repositories) error {
+			if err := repos.PasswordResetTokens.MarkUsed(ctx, token.ID.String()); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		found, err := NewPasswordResetToken(db).FindByHash(context.Background(), entities.HashPasswordResetToken(plain))
+		assert.NoError(t, err)
+		assert.False(t, found.IsUsed())
+	})
+}
+
+func TestWithinTransactionRollsBackOnPanic(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		product, _ := entities.NewProduct("Product 1", 10.0)
+
+		assert.Panics(t, func() {
+			NewTransactionManager(db).WithinTransaction(context.Background(), func(ctx context.Context, repos Repositories) error {
+				repos.Products.Create(ctx, product)
+				panic("boom")
+			})
+		})
+
+		_, err := NewProduct(db).FindByID(context.Background(), product.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestWithinTransactionNestedSavepoint(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		outer, _ := entities.NewProduct("Outer", 10.0)
+		inner, _ := entities.NewProduct("Inner", 20.0)
+		manager := NewTransactionManager(db)
+
+		err := manager.WithinTransaction(context.Background(), func(ctx context.Context, repos Repositories) error {
+			if err := repos.Products.Create(ctx, outer); err != nil {
+				return err
+			}
+
+			err := manager.WithinTransaction(ctx, func(ctx context.Context, repos Repositories) error {
+				if err := repos.Products.Create(ctx, inner); err != nil {
+					return err
+				}
+				return errAbort
+			})
+			assert.ErrorIs(t, err, errAbort)
+
+			return nil
+		})
+		assert.NoError(t, err)
+
+		productDB := NewProduct(db)
+		_, err = productDB.FindByID(context.Background(), outer.ID.String())
+		assert.NoError(t, err)
+		_, err = productDB.FindByID(context.Background(), inner.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"gorm.io/gorm"
+)
+
+type RecoveryCode struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewRecoveryCode(db *gorm.DB) *RecoveryCode {
+	return &RecoveryCode{DB: db}
+}
+
+// Replace swaps every recovery code of a user for codes, so a new set invalidates the old one.
+func (c *RecoveryCode) Replace(ctx context.Context, userID string, codes []entities.RecoveryCode) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Use consumes the unused code of a user matching hash. It returns
+// entities.ErrInvalidTwoFactorCode when there is none.
+func (c *RecoveryCode) Use(ctx context.Context, userID, hash string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result := c.DB.WithContext(ctx).Model(&entities.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+type TwoFactorChallenge struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+func NewTwoFactorChallenge(db *gorm.DB) *TwoFactorChallenge {
+	return &TwoFactorChallenge{DB: db}
+}
+
+func (c *TwoFactorChallenge) Create(ctx context.Context, challenge *entities.TwoFactorChallenge) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	return c.DB.WithContext(ctx).Create(challenge).Error
+}
+
+func (c *TwoFactorChallenge) FindByHash(ctx context.Context, hash string) (*entities.TwoFactorChallenge, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var challenge entities.TwoFactorChallenge
+	if err := c.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&challenge).Error; err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// ReserveAttempt counts an attempt against the challenge before its code is checked. It returns
+// entities.ErrTwoFactorChallenge when the challenge is used, expired or out of attempts, so parallel
+// requests cannot try more codes than entities.MaxTwoFactorAttempts between them.
+func (c *TwoFactorChallenge) ReserveAttempt(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result := c.DB.WithContext(ctx).Model(&entities.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ? AND expires_at > ?", id, entities.MaxTwoFactorAttempts, time.Now()).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrTwoFactorChallenge
+	}
+
+	return nil
+}
+
+// MarkUsed consumes a challenge. It returns entities.ErrTwoFactorChallenge when it was already used,
+// so one challenge cannot be completed twice.
+func (c *TwoFactorChallenge) MarkUsed(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result := c.DB.WithContext(ctx).Model(&entities.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrTwoFactorChallenge
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		userID := entityPkg.NewID()
+		codeDB := NewRecoveryCode(db)
+
+		oldCodes, oldPlains, _ := entities.NewRecoveryCodes(userID)
+		assert.NoError(t, codeDB.Replace(context.Background(), userID.String(), oldCodes))
+
+		codes, plains, _ := entities.NewRecoveryCodes(userID)
+		assert.NoError(t, codeDB.Replace(context.Background(), userID.String(), codes))
+
+		err := codeDB.Use(context.Background(), userID.String(), entities.HashRecoveryCode(oldPlains[0]))
+		assert.ErrorIs(t, err, entities.ErrInvalidTwoFactorCode)
+
+		err = codeDB.Use(context.Background(), userID.String(), entities.HashRecoveryCode(plains[0]))
+		assert.NoError(t, err)
+		err = codeDB.Use(context.Background(), userID.String(), entities.HashRecoveryCode(plains[0]))
+		assert.ErrorIs(t, err, entities.ErrInvalidTwoFactorCode)
+
+		err = codeDB.Use(context.Background(), entityPkg.NewID().String(), entities.HashRecoveryCode(plains[1]))
+		assert.ErrorIs(t, err, entities.ErrInvalidTwoFactorCode)
+	})
+}
+
+func TestTwoFactorChallenge(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		challenge, plain, _ := entities.NewTwoFactorChallenge(entityPkg.NewID(), time.Minute)
+
+		challengeDB := NewTwoFactorChallenge(db)
+		assert.NoError(t, challengeDB.Create(context.Background(), challenge))
+
+		assert.NoError(t, challengeDB.ReserveAttempt(context.Background(), challenge.ID.String()))
+		assert.NoError(t, challengeDB.ReserveAttempt(context.Background(), challenge.ID.String()))
+
+		found, err := challengeDB.FindByHash(context.Background(), entities.HashTwoFactorChallenge(plain))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, found.Attempts)
+		assert.True(t, found.IsOpen())
+
+		assert.NoError(t, challengeDB.MarkUsed(context.Background(), challenge.ID.String()))
+		assert.ErrorIs(t, challengeDB.MarkUsed(context.Background(), challenge.ID.String()), entities.ErrTwoFactorChallenge)
+
+		_, err = challengeDB.FindByHash(context.Background(), entities.HashTwoFactorChallenge("unknown"))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestReserveTwoFactorAttempt(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		challengeDB := NewTwoFactorChallenge(db)
+
+		challenge, _, _ := entities.NewTwoFactorChallenge(entityPkg.NewID(), time.Minute)
+		assert.NoError(t, challengeDB.Create(context.Background(), challenge))
+		for i := 0; i < entities.MaxTwoFactorAttempts; i++ {
+			assert.NoError(t, challengeDB.ReserveAttempt(context.Background(), challenge.ID.String()))
+		}
+		err := challengeDB.ReserveAttempt(context.Background(), challenge.ID.String())
+		assert.ErrorIs(t, err, entities.ErrTwoFactorChallenge)
+
+		used, _, _ := entities.NewTwoFactorChallenge(entityPkg.NewID(), time.Minute)
+		assert.NoError(t, challengeDB.Create(context.Background(), used))
+		assert.NoError(t, challengeDB.MarkUsed(context.Background(), used.ID.String()))
+		err = challengeDB.ReserveAttempt(context.Background(), used.ID.String())
+		assert.ErrorIs(t, err, entities.ErrTwoFactorChallenge)
+
+		expired, _, _ := entities.NewTwoFactorChallenge(entityPkg.NewID(), -time.Minute)
+		assert.NoError(t, challengeDB.Create(context.Background(), expired))
+		err = challengeDB.ReserveAttempt(context.Background(), expired.ID.String())
+		assert.ErrorIs(t, err, entities.ErrTwoFactorChallenge)
+	})
+}
+
+func TestUpdateUserTOTP(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+		userDB := NewUser(db)
+		assert.NoError(t, userDB.Create(context.Background(), user))
+
+		secret, _ := user.BeginTOTPEnrollment()
+		user.TOTPEnabled = true
+		user.TOTPLastStep = 42
+		assert.NoError(t, userDB.UpdateTOTP(context.Background(), user))
+
+		// the step only moves through UpdateTOTPStep
+		found, err := userDB.FindByID(context.Background(), user.ID.String())
+		assert.NoError(t, err)
+		assert.True(t, found.TOTPEnabled)
+		assert.Equal(t, secret, found.TOTPSecret)
+		assert.Equal(t, int64(0), found.TOTPLastStep)
+
+		err = userDB.UpdateTOTPStep(context.Background(), user.ID.String(), 42)
+		assert.NoError(t, err)
+		err = userDB.UpdateTOTPStep(context.Background(), user.ID.String(), 42)
+		assert.ErrorIs(t, err, entities.ErrInvalidTwoFactorCode)
+
+		found.DisableTOTP()
+		assert.NoError(t, userDB.UpdateTOTP(context.Background(), found))
+		found, _ = userDB.FindByID(context.Background(), user.ID.String())
+		assert.False(t, found.TOTPEnabled)
+		assert.Empty(t, found.TOTPSecret)
+		assert.Equal(t, int64(42), found.TOTPLastStep)
+	})
+}
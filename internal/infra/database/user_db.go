@@ -1,27 +1,189 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/caiocp/go-api/internal/entities"
 	"gorm.io/gorm"
 )
 
 type User struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Timeout time.Duration
 }
 
 func NewUser(db *gorm.DB) *User {
 	return &User{DB: db}
 }
 
-func (u *User) Create(user *entities.User) error {
-	return u.DB.Create(user).Error
+func (u *User) Create(ctx context.Context, user *entities.User) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	err := u.DB.WithContext(ctx).Create(user).Error
+	if isUniqueViolation(err) {
+		return entities.ErrEmailTaken
+	}
+
+	return err
 }
 
-func (u *User) FindByEmail(email string) (*entities.User, error) {
+func (u *User) FindByID(ctx context.Context, id string) (*entities.User, error) {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
 	var user entities.User
-	if err := u.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := u.DB.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
+
+func (u *User) FindByEmail(ctx context.Context, email string) (*entities.User, error) {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	var user entities.User
+	if err := u.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdateProfile stores the name, email and verified flag of user. It returns entities.ErrEmailTaken
+// when another account uses the address.
+func (u *User) UpdateProfile(ctx context.Context, user *entities.User) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	result := u.DB.WithContext(ctx).Model(user).
+		Select("name", "email", "verified").
+		Updates(user)
+	if isUniqueViolation(result.Error) {
+		return entities.ErrEmailTaken
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// MarkVerified flags the account as verified while its address is still email, so a link cannot
+// vouch for an address that changed after it was checked.
+func (u *User) MarkVerified(ctx context.Context, id, email string) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	result := u.DB.WithContext(ctx).Model(&entities.User{}).
+		Where("id = ? AND email = ?", id, email).
+		Update("verified", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (u *User) UpdatePassword(ctx context.Context, id, hash string) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	result := u.DB.WithContext(ctx).Model(&entities.User{}).Where("id = ?", id).Update("password", hash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// UpdateTOTP stores the two-factor secret and whether it is enabled. The last accepted step is left
+// to UpdateTOTPStep.
+func (u *User) UpdateTOTP(ctx context.Context, user *entities.User) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	result := u.DB.WithContext(ctx).Model(user).
+		Select("totp_enabled", "totp_secret").
+		Updates(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (u *User) UpdateRole(ctx context.Context, id string, role entities.Role) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	result := u.DB.WithContext(ctx).Model(&entities.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// UpdateTOTPStep records the period of the last accepted TOTP code. It only moves forward and returns
+// entities.ErrInvalidTwoFactorCode when another request already used this code or a newer one.
+func (u *User) UpdateTOTPStep(ctx context.Context, id string, step int64) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	result := u.DB.WithContext(ctx).Model(&entities.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// ReplacePasswordHash swaps oldHash for newHash, as when a hash is upgraded at login. Nothing happens
+// when the password was changed in the meantime, so the upgrade never undoes a newer password.
+func (u *User) ReplacePasswordHash(ctx context.Context, id, oldHash, newHash string) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	return u.DB.WithContext(ctx).Model(&entities.User{}).
+		Where("id = ? AND password = ?", id, oldHash).
+		Update("password", newHash).Error
+}
+
+func (u *User) Delete(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, u.Timeout)
+	defer cancel()
+
+	result := u.DB.WithContext(ctx).Delete(&entities.User{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
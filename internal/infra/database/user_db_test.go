@@ -1,57 +1,205 @@
 package database
 
 import (
+	"context"
 	"testing"
 
 	"github.com/caiocp/go-api/internal/entities"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func TestCreateUser(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
-	if err != nil {
-		t.Error(err)
-	}
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
 
-	db.AutoMigrate(entities.User{})
+		userDB := NewUser(db)
 
-	user, _ := entities.NewUser("caio", "caio@caio.com", "123456")
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
 
-	userDB := NewUser(db)
+		var userFound entities.User
+		err = db.First(&userFound, "id = ?", user.ID).Error
+		assert.Nil(t, err)
+		assert.Equal(t, user.ID, userFound.ID)
+		assert.Equal(t, user.Name, userFound.Name)
+		assert.Equal(t, user.Email, userFound.Email)
+		assert.NotNil(t, user.Password)
+	})
+}
+
+func TestFindUserByEmail(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
 
-	err = userDB.Create(user)
-	assert.Nil(t, err)
+		userDB := NewUser(db)
 
-	var userFound entities.User
-	err = db.First(&userFound, "id = ?", user.ID).Error
-	assert.Nil(t, err)
-	assert.Equal(t, user.ID, userFound.ID)
-	assert.Equal(t, user.Name, userFound.Name)
-	assert.Equal(t, user.Email, userFound.Email)
-	assert.NotNil(t, user.Password)
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
+
+		userFound, err := userDB.FindByEmail(context.Background(), "caio@caio.com")
+		assert.Nil(t, err)
+		assert.Equal(t, user.ID, userFound.ID)
+		assert.Equal(t, user.Name, userFound.Name)
+		assert.Equal(t, user.Email, userFound.Email)
+		assert.NotNil(t, user.Password)
+	})
 }
 
-func TestFindUserByEmail(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
-	if err != nil {
-		t.Error(err)
-	}
+func TestUpdateUserRole(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+
+		userDB := NewUser(db)
+
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
+
+		err = userDB.UpdateRole(context.Background(), user.ID.String(), entities.RoleAdmin)
+		assert.Nil(t, err)
+
+		userFound, err := userDB.FindByEmail(context.Background(), "caio@caio.com")
+		assert.Nil(t, err)
+		assert.Equal(t, entities.RoleAdmin, userFound.Role)
+
+		err = userDB.UpdateRole(context.Background(), "unknown", entities.RoleAdmin)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestFindUserByID(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+
+		userDB := NewUser(db)
+
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
+
+		userFound, err := userDB.FindByID(context.Background(), user.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, user.Email, userFound.Email)
+
+		_, err = userDB.FindByID(context.Background(), "unknown")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+
+		userDB := NewUser(db)
+
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
+
+		// a profile update leaves the password of a stale copy alone
+		stale := *user
+		assert.Nil(t, user.SetPassword("abcdefgh"))
+		err = userDB.UpdatePassword(context.Background(), user.ID.String(), user.Password)
+		assert.Nil(t, err)
+
+		stale.Name = "caio carvalho"
+		stale.SetEmail("carvalho@caio.com")
+		err = userDB.UpdateProfile(context.Background(), &stale)
+		assert.Nil(t, err)
+
+		userFound, err := userDB.FindByID(context.Background(), user.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, "caio carvalho", userFound.Name)
+		assert.Equal(t, "carvalho@caio.com", userFound.Email)
+		assert.True(t, userFound.ValidatePassword("abcdefgh"))
+		assert.Equal(t, entities.RoleViewer, userFound.Role)
+
+		missing, _ := entities.NewUser("ghost", "ghost@caio.com", "12345678")
+		err = userDB.UpdateProfile(context.Background(), missing)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		err = userDB.UpdatePassword(context.Background(), missing.ID.String(), missing.Password)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestMarkUserVerified(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+
+		userDB := NewUser(db)
+
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
+
+		err = userDB.MarkVerified(context.Background(), user.ID.String(), "old@caio.com")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		userFound, _ := userDB.FindByID(context.Background(), user.ID.String())
+		assert.False(t, userFound.Verified)
+
+		err = userDB.MarkVerified(context.Background(), user.ID.String(), user.Email)
+		assert.Nil(t, err)
+		userFound, _ = userDB.FindByID(context.Background(), user.ID.String())
+		assert.True(t, userFound.Verified)
+	})
+}
+
+func TestReplacePasswordHash(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+
+		userDB := NewUser(db)
+
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
+
+		err = userDB.ReplacePasswordHash(context.Background(), user.ID.String(), "stale", "ignored")
+		assert.Nil(t, err)
+		userFound, _ := userDB.FindByID(context.Background(), user.ID.String())
+		assert.Equal(t, user.Password, userFound.Password)
+
+		err = userDB.ReplacePasswordHash(context.Background(), user.ID.String(), user.Password, "upgraded")
+		assert.Nil(t, err)
+		userFound, _ = userDB.FindByID(context.Background(), user.ID.String())
+		assert.Equal(t, "upgraded", userFound.Password)
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+
+		userDB := NewUser(db)
+
+		err := userDB.Create(context.Background(), user)
+		assert.Nil(t, err)
+
+		err = userDB.Delete(context.Background(), user.ID.String())
+		assert.Nil(t, err)
+
+		_, err = userDB.FindByID(context.Background(), user.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		err = userDB.Delete(context.Background(), user.ID.String())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
 
-	db.AutoMigrate(entities.User{})
+func TestUserEmailIsUnique(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		userDB := NewUser(db)
 
-	user, _ := entities.NewUser("caio", "caio@caio.com", "123456")
+		first, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+		err := userDB.Create(context.Background(), first)
+		assert.Nil(t, err)
 
-	userDB := NewUser(db)
+		duplicate, _ := entities.NewUser("other", " CAIO@caio.com", "12345678")
+		err = userDB.Create(context.Background(), duplicate)
+		assert.ErrorIs(t, err, entities.ErrEmailTaken)
 
-	err = userDB.Create(user)
-	assert.Nil(t, err)
+		second, _ := entities.NewUser("other", "other@caio.com", "12345678")
+		err = userDB.Create(context.Background(), second)
+		assert.Nil(t, err)
 
-	userFound, err := userDB.FindByEmail("caio@caio.com")
-	assert.Nil(t, err)
-	assert.Equal(t, user.ID, userFound.ID)
-	assert.Equal(t, user.Name, userFound.Name)
-	assert.Equal(t, user.Email, userFound.Email)
-	assert.NotNil(t, user.Password)
+		second.Email = first.Email
+		err = userDB.UpdateProfile(context.Background(), second)
+		assert.ErrorIs(t, err, entities.ErrEmailTaken)
+	})
 }
@@ -0,0 +1,222 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported JWT algorithm")
+	ErrKeyMismatch          = errors.New("key does not match the JWT algorithm")
+	ErrNoSigningKey         = errors.New("the first JWT key must be a private key")
+	ErrUnknownKey           = errors.New("token signed with an unknown key")
+	ErrAlgorithmMismatch    = errors.New("token signed with an unexpected algorithm")
+)
+
+// KeySet signs tokens with one active key and verifies them against every key still trusted, so
+// a new key can be introduced while tokens signed by the previous one remain valid. Tokens carry
+// the kid of the key that signed them.
+type KeySet struct {
+	alg     jwa.SignatureAlgorithm
+	kid     string
+	signKey interface{}
+	verify  map[string]interface{}
+	public  jwk.Set
+}
+
+// NewHMAC builds a key set for HS256, HS384 or HS512 around a shared secret. Nothing is published
+// in the JWKS for symmetric keys.
+func NewHMAC(alg string, secret []byte) (*KeySet, error) {
+	algorithm := jwa.SignatureAlgorithm(alg)
+	switch algorithm {
+	case jwa.HS256, jwa.HS384, jwa.HS512:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	return &KeySet{
+		alg:     algorithm,
+		signKey: secret,
+		verify:  map[string]interface{}{"": secret},
+		public:  jwk.NewSet(),
+	}, nil
+}
+
+// LoadPEM builds a key set for RS256, ES256 or EdDSA from PEM files. The first file must hold the
+// private key that signs new tokens; the others may hold private or public keys that are only
+// used to verify tokens during a rotation.
+func LoadPEM(alg string, paths []string) (*KeySet, error) {
+	algorithm := jwa.SignatureAlgorithm(alg)
+	switch algorithm {
+	case jwa.RS256, jwa.ES256, jwa.EdDSA:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	ks := &KeySet{alg: algorithm, verify: map[string]interface{}{}, public: jwk.NewSet()}
+	for i, path := range paths {
+		data, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+
+		private, public, err := parsePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if !matchesAlgorithm(algorithm, public) {
+			return nil, fmt.Errorf("%s: %w %s", path, ErrKeyMismatch, alg)
+		}
+
+		key, err := jwk.New(public)
+		if err != nil {
+			return nil, err
+		}
+		if err := jwk.AssignKeyID(key); err != nil {
+			return nil, err
+		}
+		key.Set(jwk.AlgorithmKey, algorithm)
+		key.Set(jwk.KeyUsageKey, "sig")
+
+		if i == 0 {
+			if private == nil {
+				return nil, ErrNoSigningKey
+			}
+			ks.kid = key.KeyID()
+			ks.signKey = private
+		}
+		ks.verify[key.KeyID()] = public
+		ks.public.Add(key)
+	}
+
+	if ks.signKey == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return ks, nil
+}
+
+func (ks *KeySet) Algorithm() string {
+	return ks.alg.String()
+}
+
+// PublicSet returns the JWKS document listing every public verification key.
+func (ks *KeySet) PublicSet() jwk.Set {
+	return ks.public
+}
+
+// Encode signs claims with the active key. It mirrors jwtauth.JWTAuth.Encode.
+func (ks *KeySet) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	token := jwt.New()
+	for k, v := range claims {
+		if err := token.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	headers := jws.NewHeaders()
+	if ks.kid != "" {
+		headers.Set(jws.KeyIDKey, ks.kid)
+	}
+
+	signed, err := jwt.Sign(token, ks.alg, ks.signKey, jwt.WithHeaders(headers))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return token, string(signed), nil
+}
+
+// Decode verifies the signature of tokenString with the key named by its kid header and parses
+// the claims. It does not validate exp, nbf or iat.
+func (ks *KeySet) Decode(tokenString string) (jwt.Token, error) {
+	message, err := jws.ParseString(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if len(message.Signatures()) != 1 {
+		return nil, ErrUnknownKey
+	}
+
+	headers := message.Signatures()[0].ProtectedHeaders()
+	if headers.Algorithm() != ks.alg {
+		return nil, ErrAlgorithmMismatch
+	}
+
+	key, ok := ks.verify[headers.KeyID()]
+	if !ok && headers.KeyID() == "" && len(ks.verify) == 1 {
+		// tokens signed before kid headers were added
+		for _, only := range ks.verify {
+			key, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return jwt.ParseString(tokenString, jwt.WithVerify(ks.alg, key))
+}
+
+func parsePEM(data []byte) (crypto.Signer, crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		return nil, public, err
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return private, private.Public(), nil
+	case "EC PRIVATE KEY":
+		private, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return private, private.Public(), nil
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		private, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+		return private, private.Public(), nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+func matchesAlgorithm(alg jwa.SignatureAlgorithm, public crypto.PublicKey) bool {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return alg == jwa.RS256
+	case *ecdsa.PublicKey:
+		return alg == jwa.ES256 && key.Curve == elliptic.P256()
+	case ed25519.PublicKey:
+		return alg == jwa.EdDSA
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKey(t *testing.T, private crypto.Signer, public bool) string {
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(private.Public())
+		assert.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		assert.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "*.pem")
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, pem.Encode(f, block))
+
+	return f.Name()
+}
+
+func generate(t *testing.T, alg string) crypto.Signer {
+	switch alg {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		return key
+	case "ES256":
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		return key
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestKeySetSignsAndVerifies(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			ks, err := LoadPEM(alg, []string{writeKey(t, generate(t, alg), false)})
+			assert.NoError(t, err)
+
+			_, signed, err := ks.Encode(map[string]interface{}{"sub": "user"})
+			assert.NoError(t, err)
+
+			message, err := jws.ParseString(signed)
+			assert.NoError(t, err)
+			assert.Equal(t, ks.kid, message.Signatures()[0].ProtectedHeaders().KeyID())
+
+			token, err := ks.Decode(signed)
+			assert.NoError(t, err)
+			assert.Equal(t, "user", token.Subject())
+
+			body, err := json.Marshal(ks.PublicSet())
+			assert.NoError(t, err)
+			assert.Contains(t, string(body), ks.kid)
+			assert.NotContains(t, string(body), `"d"`)
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, newKey := generate(t, "ES256"), generate(t, "ES256")
+
+	before, err := LoadPEM("ES256", []string{writeKey(t, oldKey, false)})
+	assert.NoError(t, err)
+	_, oldToken, err := before.Encode(map[string]interface{}{"sub": "user"})
+	assert.NoError(t, err)
+
+	// the new key signs, the old one is only trusted for verification
+	after, err := LoadPEM("ES256", []string{writeKey(t, newKey, false), writeKey(t, oldKey, true)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, after.PublicSet().Len())
+
+	_, err = after.Decode(oldToken)
+	assert.NoError(t, err)
+
+	_, newToken, err := after.Encode(map[string]interface{}{"sub": "user"})
+	assert.NoError(t, err)
+	_, err = before.Decode(newToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestLoadPEMRejectsInvalidKeys(t *testing.T) {
+	_, err := LoadPEM("RS256", []string{writeKey(t, generate(t, "ES256"), false)})
+	assert.ErrorIs(t, err, ErrKeyMismatch)
+
+	_, err = LoadPEM("ES256", []string{writeKey(t, generate(t, "ES256"), true)})
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+
+	_, err = LoadPEM("none", []string{filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestHMACKeySet(t *testing.T) {
+	ks, err := NewHMAC("HS256", []byte("secret"))
+	assert.NoError(t, err)
+
+	_, signed, err := ks.Encode(map[string]interface{}{"sub": "user"})
+	assert.NoError(t, err)
+
+	token, err := ks.Decode(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", token.Subject())
+	assert.Equal(t, 0, ks.PublicSet().Len())
+
+	other, _ := NewHMAC("HS256", []byte("other"))
+	_, err = other.Decode(signed)
+	assert.Error(t, err)
+}
+
+func TestDecodeRejectsAlgorithmSwitch(t *testing.T) {
+	hmac, _ := NewHMAC("HS256", []byte("secret"))
+	_, signed, err := hmac.Encode(map[string]interface{}{"sub": "user"})
+	assert.NoError(t, err)
+
+	ks, err := LoadPEM("EdDSA", []string{writeKey(t, generate(t, "EdDSA"), false)})
+	assert.NoError(t, err)
+
+	_, err = ks.Decode(signed)
+	assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+}
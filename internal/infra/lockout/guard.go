@@ -0,0 +1,109 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/caiocp/go-api/internal/infra/audit"
+)
+
+const (
+	ActionLocked   = "login.locked"
+	ActionUnlocked = "login.unlocked"
+)
+
+// Guard throttles logins per account and per client IP and reports lock and unlock events to the
+// audit log. Accounts are keyed by the normalized email that was tried, so unknown addresses are
+// throttled exactly like registered ones.
+type Guard struct {
+	Accounts *Tracker
+	IPs      *Tracker
+	Audit    audit.Logger
+}
+
+func NewGuard(account, ip Policy, logger audit.Logger) *Guard {
+	return &Guard{Accounts: NewTracker(account), IPs: NewTracker(ip), Audit: logger}
+}
+
+// Wait returns how long a login for email from ip has to wait, zero when it may proceed.
+func (g *Guard) Wait(ctx context.Context, email, ip string) time.Duration {
+	accountWait, accountUnlocked := g.Accounts.Wait(email)
+	if accountUnlocked {
+		g.record(ctx, ActionUnlocked, "account:"+email, "lockout expired")
+	}
+
+	ipWait, ipUnlocked := g.IPs.Wait(ip)
+	if ipUnlocked {
+		g.record(ctx, ActionUnlocked, "ip:"+ip, "lockout expired")
+	}
+
+	if ipWait > accountWait {
+		return ipWait
+	}
+	return accountWait
+}
+
+// Fail counts a failed login against both the account and the IP.
+func (g *Guard) Fail(ctx context.Context, email, ip string) {
+	if g.Accounts.Fail(email) {
+		g.record(ctx, ActionLocked, "account:"+email,
+			fmt.Sprintf("%d failed logins, locked for %s", g.Accounts.Policy.MaxFailures, g.Accounts.Policy.Lockout))
+	}
+	if g.IPs.Fail(ip) {
+		g.record(ctx, ActionLocked, "ip:"+ip,
+			fmt.Sprintf("%d failed logins, locked for %s", g.IPs.Policy.MaxFailures, g.IPs.Policy.Lockout))
+	}
+}
+
+// Succeed clears the failures of the account. The IP keeps its count so that one valid account
+// cannot be used to reset guessing against others.
+func (g *Guard) Succeed(ctx context.Context, email string) {
+	g.Accounts.Reset(email)
+}
+
+// Unlock lifts a lockout of the account ahead of time on behalf of actor.
+func (g *Guard) Unlock(ctx context.Context, email, actor string) {
+	if g.Accounts.Reset(email) {
+		g.record(ctx, ActionUnlocked, "account:"+email, "unlocked by "+actor)
+	}
+}
+
+// Sweep forgets stale failures and reports lockouts that expired without another attempt.
+func (g *Guard) Sweep(ctx context.Context) {
+	for _, email := range g.Accounts.Sweep() {
+		g.record(ctx, ActionUnlocked, "account:"+email, "lockout expired")
+	}
+	for _, ip := range g.IPs.Sweep() {
+		g.record(ctx, ActionUnlocked, "ip:"+ip, "lockout expired")
+	}
+}
+
+func (g *Guard) record(ctx context.Context, action, target, detail string) {
+	err := g.Audit.Record(ctx, audit.Event{Action: action, Target: target, Detail: detail})
+	if err != nil {
+		log.Printf("lockout: recording %s of %s: %v", action, target, err)
+	}
+}
+
+// StartSweep sweeps guard every interval until ctx is done. A non-positive interval disables it.
+func StartSweep(ctx context.Context, guard *Guard, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				guard.Sweep(ctx)
+			}
+		}
+	}()
+}
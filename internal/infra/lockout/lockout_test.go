@@ -0,0 +1,129 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/infra/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time               { return c.t }
+func (c *clock) advance(d time.Duration)      { c.t = c.t.Add(d) }
+func withClock(t *Tracker, c *clock) *Tracker { t.now = c.now; return t }
+
+type recorder struct{ events []audit.Event }
+
+func (r *recorder) Record(_ context.Context, e audit.Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+var policy = Policy{
+	MaxFailures: 3,
+	Lockout:     time.Minute,
+	BaseDelay:   time.Second,
+	MaxDelay:    3 * time.Second,
+	Window:      10 * time.Minute,
+}
+
+func TestTrackerProgressiveDelay(t *testing.T) {
+	c := &clock{t: time.Now()}
+	tracker := withClock(NewTracker(Policy{BaseDelay: time.Second, MaxDelay: 3 * time.Second, Window: time.Hour}), c)
+
+	wait, _ := tracker.Wait("key")
+	assert.Zero(t, wait)
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		assert.False(t, tracker.Fail("key"))
+		wait, _ = tracker.Wait("key")
+		assert.Equal(t, want, wait)
+	}
+
+	c.advance(3 * time.Second)
+	wait, _ = tracker.Wait("key")
+	assert.Zero(t, wait)
+}
+
+func TestTrackerLockout(t *testing.T) {
+	c := &clock{t: time.Now()}
+	tracker := withClock(NewTracker(policy), c)
+
+	assert.False(t, tracker.Fail("key"))
+	assert.False(t, tracker.Fail("key"))
+	assert.True(t, tracker.Fail("key"))
+
+	wait, unlocked := tracker.Wait("key")
+	assert.Equal(t, time.Minute, wait)
+	assert.False(t, unlocked)
+
+	c.advance(time.Minute)
+	wait, unlocked = tracker.Wait("key")
+	assert.Zero(t, wait)
+	assert.True(t, unlocked)
+	assert.Zero(t, tracker.Failures("key"))
+}
+
+func TestTrackerForgetsOldFailures(t *testing.T) {
+	c := &clock{t: time.Now()}
+	tracker := withClock(NewTracker(policy), c)
+
+	tracker.Fail("key")
+	tracker.Fail("key")
+	c.advance(11 * time.Minute)
+
+	assert.False(t, tracker.Fail("key"))
+	assert.Equal(t, 1, tracker.Failures("key"))
+
+	c.advance(11 * time.Minute)
+	assert.Empty(t, tracker.Sweep())
+	assert.Zero(t, tracker.Failures("key"))
+}
+
+func TestGuardAuditsLockAndUnlock(t *testing.T) {
+	c := &clock{t: time.Now()}
+	events := &recorder{}
+	guard := NewGuard(policy, Policy{MaxFailures: 100, Lockout: time.Minute, Window: time.Hour}, events)
+	withClock(guard.Accounts, c)
+	withClock(guard.IPs, c)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, guard.Wait(ctx, "caio@caio.com", "10.0.0.1"))
+		guard.Fail(ctx, "caio@caio.com", "10.0.0.1")
+		c.advance(5 * time.Second)
+	}
+	assert.Equal(t, 55*time.Second, guard.Wait(ctx, "caio@caio.com", "10.0.0.1"))
+	assert.Zero(t, guard.Wait(ctx, "other@caio.com", "10.0.0.1"))
+
+	guard.Unlock(ctx, "caio@caio.com", "admin")
+	assert.Zero(t, guard.Wait(ctx, "caio@caio.com", "10.0.0.1"))
+
+	assert.Len(t, events.events, 2)
+	assert.Equal(t, ActionLocked, events.events[0].Action)
+	assert.Equal(t, "account:caio@caio.com", events.events[0].Target)
+	assert.Equal(t, ActionUnlocked, events.events[1].Action)
+	assert.Equal(t, "unlocked by admin", events.events[1].Detail)
+}
+
+func TestGuardSweepReportsExpiredLockouts(t *testing.T) {
+	c := &clock{t: time.Now()}
+	events := &recorder{}
+	guard := NewGuard(policy, Policy{MaxFailures: 1, Lockout: time.Minute, Window: time.Hour}, events)
+	withClock(guard.Accounts, c)
+	withClock(guard.IPs, c)
+	ctx := context.Background()
+
+	guard.Fail(ctx, "caio@caio.com", "10.0.0.1")
+	assert.Len(t, events.events, 1)
+	assert.Equal(t, "ip:10.0.0.1", events.events[0].Target)
+
+	c.advance(time.Minute)
+	guard.Sweep(ctx)
+	assert.Len(t, events.events, 2)
+	assert.Equal(t, ActionUnlocked, events.events[1].Action)
+	assert.Equal(t, "ip:10.0.0.1", events.events[1].Target)
+}
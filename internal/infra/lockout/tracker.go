@@ -0,0 +1,156 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy configures how a Tracker slows down and locks out a key after failed attempts.
+type Policy struct {
+	// MaxFailures consecutive failures lock the key for Lockout. Zero disables lockout.
+	MaxFailures int
+	Lockout     time.Duration
+
+	// BaseDelay is the wait after the first failure, doubled after each further one up to MaxDelay.
+	// Zero disables delays.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Window is how long a failure is remembered. A key with no failure in the last Window starts over.
+	Window time.Duration
+}
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Tracker counts failed attempts per key in process. Counts are per instance and lost on restart,
+// which is acceptable for slowing down guessing but means N instances allow N times the attempts.
+type Tracker struct {
+	Policy  Policy
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+func NewTracker(policy Policy) *Tracker {
+	return &Tracker{Policy: policy, entries: map[string]*entry{}, now: time.Now}
+}
+
+// Wait returns how long key has to wait before its next attempt, zero when it may try now. unlocked
+// is true the first time Wait sees that a lockout of key has run out.
+func (t *Tracker) Wait(key string) (wait time.Duration, unlocked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return 0, false
+	}
+
+	now := t.now()
+	if !e.lockedUntil.IsZero() {
+		if now.Before(e.lockedUntil) {
+			return e.lockedUntil.Sub(now), false
+		}
+		delete(t.entries, key)
+		return 0, true
+	}
+	if now.Sub(e.lastFailure) > t.Policy.Window {
+		delete(t.entries, key)
+		return 0, false
+	}
+
+	if ready := e.lastFailure.Add(t.delay(e.failures)); now.Before(ready) {
+		return ready.Sub(now), false
+	}
+
+	return 0, false
+}
+
+// Fail records a failed attempt and reports whether it locked key.
+func (t *Tracker) Fail(key string) (locked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e, ok := t.entries[key]
+	if !ok || e.lockedUntil.IsZero() && now.Sub(e.lastFailure) > t.Policy.Window {
+		e = &entry{}
+		t.entries[key] = e
+	}
+
+	e.failures++
+	e.lastFailure = now
+	if t.Policy.MaxFailures > 0 && e.failures >= t.Policy.MaxFailures && e.lockedUntil.IsZero() {
+		e.lockedUntil = now.Add(t.Policy.Lockout)
+		return true
+	}
+
+	return false
+}
+
+// Reset forgets every failure of key and reports whether it was locked at the time.
+func (t *Tracker) Reset(key string) (wasLocked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return false
+	}
+	delete(t.entries, key)
+
+	return t.now().Before(e.lockedUntil)
+}
+
+// Failures returns the number of failures currently counted against key.
+func (t *Tracker) Failures(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e, ok := t.entries[key]; ok {
+		return e.failures
+	}
+
+	return 0
+}
+
+// Sweep drops forgotten failures and expired lockouts, and returns the keys whose lockout expired.
+func (t *Tracker) Sweep() (unlocked []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for key, e := range t.entries {
+		switch {
+		case !e.lockedUntil.IsZero():
+			if !now.Before(e.lockedUntil) {
+				delete(t.entries, key)
+				unlocked = append(unlocked, key)
+			}
+		case now.Sub(e.lastFailure) > t.Policy.Window:
+			delete(t.entries, key)
+		}
+	}
+
+	return unlocked
+}
+
+func (t *Tracker) delay(failures int) time.Duration {
+	if t.Policy.BaseDelay <= 0 || failures == 0 {
+		return 0
+	}
+
+	delay := t.Policy.BaseDelay
+	for i := 1; i < failures && delay < t.Policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if t.Policy.MaxDelay > 0 && delay > t.Policy.MaxDelay {
+		delay = t.Policy.MaxDelay
+	}
+
+	return delay
+}
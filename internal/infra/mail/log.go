@@ -0,0 +1,29 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Log writes every message to W instead of delivering it. It is meant for development and tests,
+// where W is usually os.Stdout or a file the developer can read tokens from.
+type Log struct {
+	W  io.Writer
+	mu sync.Mutex
+}
+
+func NewLog(w io.Writer) *Log {
+	return &Log{W: w}
+}
+
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := fmt.Fprintf(l.W, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().UTC().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	return err
+}
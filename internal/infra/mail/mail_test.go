@@ -0,0 +1,86 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogWritesMessage(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := NewLog(&buf).Send(context.Background(), Message{To: "caio@caio.com", Subject: "Hello", Body: "token: abc"})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "To: caio@caio.com\n")
+	assert.Contains(t, buf.String(), "Subject: Hello\n")
+	assert.Contains(t, buf.String(), "token: abc")
+}
+
+// fakeSMTP accepts a single message without authentication and returns its DATA section.
+func fakeSMTP(t *testing.T) (string, string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var body strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					body.WriteString(line)
+				}
+				data <- body.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port, data
+}
+
+func TestSMTPSendsMessage(t *testing.T) {
+	host, port, data := fakeSMTP(t)
+
+	mailer := NewSMTP(host, port, "", "", "no-reply@caio.com")
+	err := mailer.Send(context.Background(), Message{To: "caio@caio.com", Subject: "Hello", Body: "line one\nline two"})
+	assert.NoError(t, err)
+
+	received := <-data
+	assert.Contains(t, received, "From: no-reply@caio.com\r\n")
+	assert.Contains(t, received, "To: caio@caio.com\r\n")
+	assert.Contains(t, received, "Subject: Hello\r\n")
+	assert.Contains(t, received, "line one\r\nline two")
+}
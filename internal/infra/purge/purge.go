@@ -0,0 +1,45 @@
+// Package purge removes products that have stayed in the trash longer than the retention period.
+package purge
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Purger permanently removes the entries that were trashed before a given time.
+type Purger interface {
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Run purges the entries trashed more than retention before now.
+func Run(ctx context.Context, purger Purger, retention time.Duration, now time.Time) (int64, error) {
+	return purger.PurgeDeleted(ctx, now.Add(-retention))
+}
+
+// Start purges the entries trashed more than retention ago every interval until ctx is done. A
+// non-positive retention or interval disables purging, keeping the trash until it is emptied by hand.
+func Start(ctx context.Context, purger Purger, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				purged, err := Run(ctx, purger, retention, now)
+				if err != nil {
+					log.Printf("purge: emptying the trash: %v", err)
+				} else if purged > 0 {
+					log.Printf("purge: removed %d entries from the trash", purged)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,62 @@
+package purge
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePurger struct {
+	mu     sync.Mutex
+	before []time.Time
+}
+
+func (f *fakePurger) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.before = append(f.before, before)
+	return 1, nil
+}
+
+func (f *fakePurger) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.before)
+}
+
+func TestRunPurgesBeforeRetention(t *testing.T) {
+	purger := &fakePurger{}
+	now := time.Now()
+
+	purged, err := Run(context.Background(), purger, time.Hour, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Equal(t, []time.Time{now.Add(-time.Hour)}, purger.before)
+}
+
+func TestStartPurgesEveryInterval(t *testing.T) {
+	purger := &fakePurger{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Start(ctx, purger, time.Hour, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return purger.calls() >= 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestStartDisabled(t *testing.T) {
+	purger := &fakePurger{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Start(ctx, purger, 0, 10*time.Millisecond)
+	Start(ctx, purger, time.Hour, 0)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, purger.calls())
+}
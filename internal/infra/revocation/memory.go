@@ -0,0 +1,84 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type subjectEntry struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// Memory keeps revocations in process. On its own it only suits a single instance, since entries
+// are lost on restart.
+type Memory struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	subjects map[string]subjectEntry
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		tokens:   map[string]time.Time{},
+		subjects: map[string]subjectEntry{},
+	}
+}
+
+func (m *Memory) RevokeToken(_ context.Context, jti, _ string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[jti] = expiresAt
+	return nil
+}
+
+func (m *Memory) RevokeSubject(_ context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.subjects[subject]
+	if issuedBefore.After(entry.issuedBefore) {
+		entry.issuedBefore = issuedBefore
+	}
+	if expiresAt.After(entry.expiresAt) {
+		entry.expiresAt = expiresAt
+	}
+	m.subjects[subject] = entry
+
+	return nil
+}
+
+func (m *Memory) IsRevoked(_ context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	if expiresAt, ok := m.tokens[jti]; ok && jti != "" && now.Before(expiresAt) {
+		return true, nil
+	}
+	if entry, ok := m.subjects[subject]; ok && now.Before(entry.expiresAt) && !issuedAt.After(entry.issuedBefore) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (m *Memory) DeleteExpired(_ context.Context, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for jti, expiresAt := range m.tokens {
+		if !now.Before(expiresAt) {
+			delete(m.tokens, jti)
+		}
+	}
+	for subject, entry := range m.subjects {
+		if !now.Before(entry.expiresAt) {
+			delete(m.subjects, subject)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	now := time.Now()
+
+	assert.NoError(t, store.RevokeToken(ctx, "jti-1", "user", now.Add(time.Minute)))
+
+	revoked, err := store.IsRevoked(ctx, "jti-1", "user", now)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsRevoked(ctx, "jti-2", "user", now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestMemoryRevokeSubject(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	now := time.Now()
+
+	assert.NoError(t, store.RevokeSubject(ctx, "user", now, now.Add(time.Minute)))
+
+	revoked, _ := store.IsRevoked(ctx, "old", "user", now.Add(-time.Second))
+	assert.True(t, revoked)
+
+	revoked, _ = store.IsRevoked(ctx, "new", "user", now.Add(time.Second))
+	assert.False(t, revoked)
+
+	revoked, _ = store.IsRevoked(ctx, "old", "other", now.Add(-time.Second))
+	assert.False(t, revoked)
+}
+
+func TestMemoryDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	now := time.Now()
+
+	assert.NoError(t, store.RevokeToken(ctx, "expired", "user", now.Add(-time.Second)))
+	assert.NoError(t, store.RevokeToken(ctx, "live", "user", now.Add(time.Minute)))
+	assert.NoError(t, store.RevokeSubject(ctx, "user", now, now.Add(-time.Second)))
+
+	assert.NoError(t, store.DeleteExpired(ctx, now))
+	assert.Len(t, store.tokens, 1)
+	assert.Empty(t, store.subjects)
+}
+
+func TestLayeredFallsBackToPersistent(t *testing.T) {
+	ctx := context.Background()
+	persistent := NewMemory()
+	store := NewLayered(persistent)
+	now := time.Now()
+
+	// written by another instance, so only the persistent store knows about it
+	assert.NoError(t, persistent.RevokeToken(ctx, "jti-1", "user", now.Add(time.Minute)))
+
+	revoked, err := store.IsRevoked(ctx, "jti-1", "user", now)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	assert.NoError(t, store.RevokeToken(ctx, "jti-2", "user", now.Add(time.Minute)))
+	revoked, _ = store.Memory.IsRevoked(ctx, "jti-2", "user", now)
+	assert.True(t, revoked)
+	revoked, _ = persistent.IsRevoked(ctx, "jti-2", "user", now)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,83 @@
+package revocation
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Store records access tokens that must be refused before they expire.
+type Store interface {
+	RevokeToken(ctx context.Context, jti, subject string, expiresAt time.Time) error
+	RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// Layered answers from memory and falls back to the persistent store, which is the source of truth
+// shared by every instance and kept across restarts.
+type Layered struct {
+	Memory     *Memory
+	Persistent Store
+}
+
+func NewLayered(persistent Store) *Layered {
+	return &Layered{Memory: NewMemory(), Persistent: persistent}
+}
+
+func (l *Layered) RevokeToken(ctx context.Context, jti, subject string, expiresAt time.Time) error {
+	if err := l.Persistent.RevokeToken(ctx, jti, subject, expiresAt); err != nil {
+		return err
+	}
+
+	return l.Memory.RevokeToken(ctx, jti, subject, expiresAt)
+}
+
+func (l *Layered) RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	if err := l.Persistent.RevokeSubject(ctx, subject, issuedBefore, expiresAt); err != nil {
+		return err
+	}
+
+	return l.Memory.RevokeSubject(ctx, subject, issuedBefore, expiresAt)
+}
+
+func (l *Layered) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	revoked, err := l.Memory.IsRevoked(ctx, jti, subject, issuedAt)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	return l.Persistent.IsRevoked(ctx, jti, subject, issuedAt)
+}
+
+func (l *Layered) DeleteExpired(ctx context.Context, now time.Time) error {
+	if err := l.Memory.DeleteExpired(ctx, now); err != nil {
+		return err
+	}
+
+	return l.Persistent.DeleteExpired(ctx, now)
+}
+
+// StartGC deletes expired entries from store every interval until ctx is done. A non-positive
+// interval disables collection.
+func StartGC(ctx context.Context, store Store, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := store.DeleteExpired(ctx, now); err != nil {
+					log.Printf("revocation: deleting expired entries: %v", err)
+				}
+			}
+		}
+	}()
+}
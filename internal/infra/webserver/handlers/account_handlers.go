@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/go-chi/chi/v5"
+)
+
+// Get current user godoc
+// @Summary Get the current user
+// @Description Return the account the access token was issued to
+// @Tags users
+// @Produce  json
+// @Success 200 {object} entities.User
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me [get]
+// @Security ApiKeyAuth
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	h.getUser(w, r, middlewares.SubjectFromContext(r))
+}
+
+// Update current user godoc
+// @Summary Update the current user
+// @Description Change the name or email of the current account. Omitted fields are left unchanged. A
+// @Description new email address has to be verified again.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.UpdateUserInput true "User request"
+// @Success 200 {object} entities.User
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me [patch]
+// @Security ApiKeyAuth
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, middlewares.SubjectFromContext(r))
+}
+
+// Delete current user godoc
+// @Summary Delete the current user
+// @Description Delete the current account and revoke every token issued to it
+// @Tags users
+// @Produce  json
+// @Success 204
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me [delete]
+// @Security ApiKeyAuth
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	h.deleteUser(w, r, middlewares.SubjectFromContext(r))
+}
+
+// Change password godoc
+// @Summary Change the password of the current user
+// @Description Set a new password after checking the current one. It has to be PASSWORD_MIN_LENGTH long
+// @Description and not on the breached password list. Refresh tokens issued before the change stop working.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.ChangePasswordInput true "Password request"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me/password [put]
+// @Security ApiKeyAuth
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var input dtos.ChangePasswordInput
+
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), middlewares.SubjectFromContext(r))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = u.ChangePassword(input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.userDB.UpdatePassword(r.Context(), u.ID.String(), u.Password)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.refreshTokenDB.RevokeUser(r.Context(), u.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get user godoc
+// @Summary Get a user
+// @Description Requires the users:manage permission.
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID" Format(uuid)
+// @Success 200 {object} entities.User
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/{id} [get]
+// @Security ApiKeyAuth
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	h.getUser(w, r, chi.URLParam(r, "id"))
+}
+
+// Update user godoc
+// @Summary Update a user
+// @Description Change the name or email of a user. Omitted fields are left unchanged. A new email
+// @Description address has to be verified again. Requires the users:manage permission.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID" Format(uuid)
+// @Param request body dtos.UpdateUserInput true "User request"
+// @Success 200 {object} entities.User
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/{id} [patch]
+// @Security ApiKeyAuth
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, chi.URLParam(r, "id"))
+}
+
+// Delete user godoc
+// @Summary Delete a user
+// @Description Delete a user and revoke every token issued to them. Requires the users:manage permission.
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID" Format(uuid)
+// @Success 204
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/{id} [delete]
+// @Security ApiKeyAuth
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	h.deleteUser(w, r, chi.URLParam(r, "id"))
+}
+
+func (h *UserHandler) getUser(w http.ResponseWriter, r *http.Request, id string) {
+	u, err := h.userDB.FindByID(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, id string) {
+	var input dtos.UpdateUserInput
+
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		u.Name = *input.Name
+	}
+	if input.Email != nil {
+		u.SetEmail(*input.Email)
+	}
+
+	err = h.userDB.UpdateProfile(r.Context(), u)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if !u.Verified {
+		err = h.sendVerification(r, u)
+		if err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+func (h *UserHandler) deleteUser(w http.ResponseWriter, r *http.Request, id string) {
+	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+
+	err := h.userDB.Delete(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// access tokens outlive the account until they expire, so cut them off explicitly
+	now := time.Now()
+	err = h.revocations.RevokeSubject(r.Context(), id, now, now.Add(time.Second*time.Duration(jwtExpiresIn)))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.refreshTokenDB.RevokeUser(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeyHandler struct {
+	apiKeyDB database.APIKeyInterface
+}
+
+func NewAPIKeyHandler(apiKeyDB database.APIKeyInterface) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyDB: apiKeyDB}
+}
+
+// Create API key godoc
+// @Summary Create an API key
+// @Description Create a personal API key to send in the X-API-Key header. Without scopes the key
+// @Description has every permission of the account's role. The key is only shown in this response.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.CreateAPIKeyInput true "API key"
+// @Success 201 {object} dtos.CreateAPIKeyOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me/api_keys [post]
+// @Security ApiKeyAuth
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var input dtos.CreateAPIKeyInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	userID, err := entityPkg.ParseID(middlewares.SubjectFromContext(r))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	key, plain, err := entities.NewAPIKey(userID, input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.apiKeyDB.Create(r.Context(), key)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dtos.CreateAPIKeyOutput{APIKey: *key, Key: plain})
+}
+
+// List API keys godoc
+// @Summary List API keys
+// @Description List the current account's API keys, newest first, including revoked and expired ones.
+// @Tags users
+// @Produce  json
+// @Success 200 {array} entities.APIKey
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me/api_keys [get]
+// @Security ApiKeyAuth
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeyDB.FindByUser(r.Context(), middlewares.SubjectFromContext(r))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// Revoke API key godoc
+// @Summary Revoke an API key
+// @Description The key stops working immediately and stays listed with its revocation time.
+// @Tags users
+// @Param id path string true "API key ID" Format(uuid)
+// @Success 204
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me/api_keys/{id} [delete]
+// @Security ApiKeyAuth
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := h.apiKeyDB.Revoke(r.Context(), middlewares.SubjectFromContext(r), chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
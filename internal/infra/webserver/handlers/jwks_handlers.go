@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/infra/jwks"
+)
+
+type JWKSHandler struct {
+	keys *jwks.KeySet
+}
+
+func NewJWKSHandler(keys *jwks.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// Get JWKS godoc
+// @Summary Get JSON Web Key Set
+// @Description Public keys that verify the access tokens issued by this API, identified by their kid.
+// @Description Empty when tokens are signed with a shared HMAC secret.
+// @Tags keys
+// @Produce  json
+// @Success 200
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.keys.PublicSet())
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/caiocp/go-api/internal/infra/mail"
+)
+
+const mailTimeout = 30 * time.Second
+
+// sendMail delivers msg in the background. The response then takes as long whether or not a
+// message was sent, and a slow relay does not hold the request open.
+func sendMail(mailer mail.Mailer, msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := mailer.Send(ctx, msg); err != nil {
+			log.Printf("mail: sending %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/go-chi/jwtauth"
+	"gorm.io/gorm"
+)
+
+var consentPage = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Authorize {{.Client}}</title></head>
+<body>
+{{if .Error}}<h1>Authorization failed</h1>
+<p>{{.Error}}</p>
+{{else}}<h1>{{.Client}} wants to access your account</h1>
+<p>Signed in as {{.Email}}. {{.Client}} is asking to:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>
+<form method="post" action="/oauth/authorize">
+{{range $name, $value := .Fields}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<button type="submit" name="decision" value="allow">Allow</button>
+<button type="submit" name="decision" value="deny">Deny</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+type consentView struct {
+	Client string
+	Email  string
+	Scopes []string
+	Fields map[string]string
+	Error  string
+}
+
+var scopeDescriptions = map[string]string{
+	string(entities.PermissionReadProducts):  "Read the product catalog",
+	string(entities.PermissionWriteProducts): "Create, change and delete products",
+}
+
+// authorizeRequest is a validated authorization request whose errors can be reported to the client
+// through its redirect URI.
+type authorizeRequest struct {
+	client        *entities.OAuthClient
+	redirectURI   string
+	scope         string
+	state         string
+	codeChallenge string
+}
+
+// Authorize godoc
+// @Summary OAuth2 authorization endpoint
+// @Description Show the signed-in user a consent page for an authorization_code request. PKCE with
+// @Description the S256 method is required. Browsers sign in with the cookie set by /oauth/login, and
+// @Description are sent there first when they have none.
+// @Tags oauth
+// @Produce  html
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string false "Registered redirect URI; optional when the client has one"
+// @Param scope query string false "Space separated scopes"
+// @Param state query string false "Opaque value returned to the client"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200
+// @Failure 302
+// @Failure 400
+// @Router /oauth/authorize [get]
+// @Security ApiKeyAuth
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.consentingUser(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := h.parseAuthorizeRequest(w, r, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	scopes := strings.Fields(req.scope)
+	for i, s := range scopes {
+		scopes[i] = scopeDescriptions[s]
+	}
+
+	renderConsent(w, http.StatusOK, consentView{
+		Client: req.client.Name,
+		Email:  u.Email,
+		Scopes: scopes,
+		Fields: map[string]string{
+			"response_type":         "code",
+			"client_id":             req.client.ID.String(),
+			"redirect_uri":          req.redirectURI,
+			"scope":                 req.scope,
+			"state":                 req.state,
+			"code_challenge":        req.codeChallenge,
+			"code_challenge_method": entities.PKCEMethodS256,
+			"consent_token":         consentToken(r),
+		},
+	})
+}
+
+// Authorize decision godoc
+// @Summary Answer an OAuth2 consent request
+// @Description Submitted by the consent page. Redirects back to the client with a code or an
+// @Description access_denied error.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Success 302
+// @Failure 400
+// @Failure 401 {object} problem.Problem
+// @Router /oauth/authorize [post]
+// @Security ApiKeyAuth
+func (h *OAuthHandler) AuthorizeDecision(w http.ResponseWriter, r *http.Request) {
+	oauthCodeExpiresIn := r.Context().Value("oauthCodeExpiresIn").(int)
+
+	u, ok := h.consentingUser(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		renderConsent(w, http.StatusBadRequest, consentView{Error: "The form could not be read."})
+		return
+	}
+
+	// the page is answered with the session that rendered it, never by a form posted from elsewhere
+	if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("consent_token")), []byte(consentToken(r))) != 1 {
+		renderConsent(w, http.StatusBadRequest, consentView{Error: "The consent form has expired. Start again from the application."})
+		return
+	}
+
+	req, ok := h.parseAuthorizeRequest(w, r, r.PostForm)
+	if !ok {
+		return
+	}
+
+	if r.PostForm.Get("decision") != "allow" {
+		redirectAuthorize(w, r, req, url.Values{"error": {oauthAccessDenied}})
+		return
+	}
+
+	code, plain, err := entities.NewAuthorizationCode(req.client.ID, u.ID, req.redirectURI, req.scope, req.codeChallenge,
+		time.Second*time.Duration(oauthCodeExpiresIn))
+	if err != nil {
+		redirectAuthorize(w, r, req, url.Values{"error": {oauthServerError}})
+		return
+	}
+
+	err = h.codeDB.Create(r.Context(), code)
+	if err != nil {
+		redirectAuthorize(w, r, req, url.Values{"error": {oauthServerError}})
+		return
+	}
+
+	redirectAuthorize(w, r, req, url.Values{"code": {plain}})
+}
+
+// consentingUser loads the signed-in user, who must have a verified email address and a full
+// first-party session.
+func (h *OAuthHandler) consentingUser(w http.ResponseWriter, r *http.Request) (*entities.User, bool) {
+	if _, scoped := middlewares.ScopeFromContext(r); scoped {
+		renderConsent(w, http.StatusForbidden, consentView{Error: "Verify your email address before authorizing applications."})
+		return nil, false
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), middlewares.SubjectFromContext(r))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		renderConsent(w, http.StatusUnauthorized, consentView{Error: "Sign in again to continue."})
+		return nil, false
+	}
+	if err != nil {
+		renderConsent(w, http.StatusInternalServerError, consentView{Error: "Something went wrong. Try again later."})
+		return nil, false
+	}
+	if !u.Verified {
+		renderConsent(w, http.StatusForbidden, consentView{Error: "Verify your email address before authorizing applications."})
+		return nil, false
+	}
+
+	return u, true
+}
+
+// parseAuthorizeRequest validates an authorization request. Requests naming an unknown client or an
+// unregistered redirect URI are answered with an error page, since redirecting them would make this
+// an open redirector; every other error goes back to the client.
+func (h *OAuthHandler) parseAuthorizeRequest(w http.ResponseWriter, r *http.Request, values url.Values) (*authorizeRequest, bool) {
+	client, err := h.clientDB.FindByID(r.Context(), values.Get("client_id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		renderConsent(w, http.StatusBadRequest, consentView{Error: "The application is not registered."})
+		return nil, false
+	}
+	if err != nil {
+		renderConsent(w, http.StatusInternalServerError, consentView{Error: "Something went wrong. Try again later."})
+		return nil, false
+	}
+
+	redirectURI := values.Get("redirect_uri")
+	if redirectURI == "" {
+		redirectURI, _ = client.DefaultRedirectURI()
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		renderConsent(w, http.StatusBadRequest, consentView{Error: "The redirect URI is not registered for this application."})
+		return nil, false
+	}
+
+	req := &authorizeRequest{
+		client:        client,
+		redirectURI:   redirectURI,
+		state:         values.Get("state"),
+		codeChallenge: values.Get("code_challenge"),
+	}
+
+	fail := func(code, description string) (*authorizeRequest, bool) {
+		redirectAuthorize(w, r, req, url.Values{"error": {code}, "error_description": {description}})
+		return nil, false
+	}
+	if values.Get("response_type") != "code" {
+		return fail(oauthUnsupportedResponseType, "response_type must be code")
+	}
+	if !client.AllowsGrant(entities.GrantAuthorizationCode) {
+		return fail(oauthUnauthorizedClient, "client is not registered for the authorization_code grant")
+	}
+	if req.codeChallenge == "" || values.Get("code_challenge_method") != entities.PKCEMethodS256 {
+		return fail(oauthInvalidRequest, "a code_challenge with code_challenge_method S256 is required")
+	}
+
+	req.scope, err = client.GrantScope(values.Get("scope"))
+	if err != nil {
+		return fail(oauthInvalidScope, err.Error())
+	}
+
+	return req, true
+}
+
+// redirectAuthorize sends the user back to the client with params and the request state.
+func redirectAuthorize(w http.ResponseWriter, r *http.Request, req *authorizeRequest, params url.Values) {
+	target, _ := url.Parse(req.redirectURI)
+	query := target.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if req.state != "" {
+		query.Set("state", req.state)
+	}
+	target.RawQuery = query.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func renderConsent(w http.ResponseWriter, status int, view consentView) {
+	renderPage(w, status, consentPage, view)
+}
+
+// renderPage writes an HTML page that must neither be cached nor framed by another site.
+func renderPage(w http.ResponseWriter, status int, page *template.Template, view interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	page.Execute(w, view)
+}
+
+// consentToken ties the consent form to the session that rendered it. It is derived from the jti of
+// the signed-in user's token, which a third-party page cannot learn.
+func consentToken(r *http.Request) string {
+	token, _, _ := jwtauth.FromContext(r.Context())
+	if token == nil {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte("oauth-consent:" + token.JwtID()))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,605 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/lestrrat-go/jwx/jwt"
+	"gorm.io/gorm"
+)
+
+// OAuth2 error codes from RFC 6749 section 5.2 and 4.1.2.1.
+const (
+	oauthInvalidRequest          = "invalid_request"
+	oauthInvalidClient           = "invalid_client"
+	oauthInvalidGrant            = "invalid_grant"
+	oauthInvalidScope            = "invalid_scope"
+	oauthUnauthorizedClient      = "unauthorized_client"
+	oauthUnsupportedGrantType    = "unsupported_grant_type"
+	oauthUnsupportedResponseType = "unsupported_response_type"
+	oauthAccessDenied            = "access_denied"
+	oauthServerError             = "server_error"
+)
+
+type OAuthHandler struct {
+	clientDB       database.OAuthClientInterface
+	codeDB         database.AuthorizationCodeInterface
+	userDB         database.UserInterface
+	refreshTokenDB database.RefreshTokenInterface
+	revocations    revocation.Store
+}
+
+func NewOAuthHandler(clientDB database.OAuthClientInterface, codeDB database.AuthorizationCodeInterface,
+	userDB database.UserInterface, refreshTokenDB database.RefreshTokenInterface, revocations revocation.Store) *OAuthHandler {
+	return &OAuthHandler{
+		clientDB:       clientDB,
+		codeDB:         codeDB,
+		userDB:         userDB,
+		refreshTokenDB: refreshTokenDB,
+		revocations:    revocations,
+	}
+}
+
+// Register OAuth client godoc
+// @Summary Register an OAuth2 client
+// @Description Requires the users:manage permission. Confidential clients get a client_secret that
+// @Description is only shown in this response; public clients may only use authorization_code with PKCE.
+// @Tags oauth
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.RegisterOAuthClientInput true "Client metadata"
+// @Success 201 {object} dtos.OAuthClientOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /oauth/clients [post]
+// @Security ApiKeyAuth
+func (h *OAuthHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	var input dtos.RegisterOAuthClientInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	client, secret, err := entities.NewOAuthClient(input.Name, input.RedirectURIs, input.GrantTypes, input.Scopes, !input.Public)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.clientDB.Create(r.Context(), client)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(clientOutput(client, secret))
+}
+
+// List OAuth clients godoc
+// @Summary List OAuth2 clients
+// @Description Requires the users:manage permission.
+// @Tags oauth
+// @Produce  json
+// @Success 200 {array} dtos.OAuthClientOutput
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /oauth/clients [get]
+// @Security ApiKeyAuth
+func (h *OAuthHandler) GetClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.clientDB.FindAll(r.Context())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	output := make([]dtos.OAuthClientOutput, len(clients))
+	for i := range clients {
+		output[i] = clientOutput(&clients[i], "")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// Delete OAuth client godoc
+// @Summary Delete an OAuth2 client
+// @Description Requires the users:manage permission. Refresh tokens issued to the client are revoked;
+// @Description its access tokens stay valid until they expire.
+// @Tags oauth
+// @Param id path string true "Client ID" Format(uuid)
+// @Success 204
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /oauth/clients/{id} [delete]
+// @Security ApiKeyAuth
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.clientDB.Delete(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.refreshTokenDB.RevokeClient(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Token godoc
+// @Summary OAuth2 token endpoint
+// @Description Supports the client_credentials, authorization_code (PKCE required) and refresh_token
+// @Description grants. Clients authenticate with HTTP Basic or client_id and client_secret form fields.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param grant_type formData string true "Grant type" Enums(client_credentials, authorization_code, refresh_token)
+// @Param scope formData string false "Space separated scopes"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used to obtain the code"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param client_id formData string false "Client ID, when not using HTTP Basic"
+// @Param client_secret formData string false "Client secret, when not using HTTP Basic"
+// @Success 200 {object} dtos.OAuthTokenOutput
+// @Failure 400 {object} dtos.OAuthErrorOutput
+// @Failure 401 {object} dtos.OAuthErrorOutput
+// @Failure 500 {object} dtos.OAuthErrorOutput
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if !parseOAuthForm(w, r) {
+		return
+	}
+
+	client, err := h.authenticateClient(r)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	grants := map[string]func(*http.Request, *entities.OAuthClient) (dtos.OAuthTokenOutput, error){
+		entities.GrantClientCredentials: h.clientCredentialsGrant,
+		entities.GrantAuthorizationCode: h.authorizationCodeGrant,
+		entities.GrantRefreshToken:      h.refreshTokenGrant,
+	}
+
+	grant := r.PostForm.Get("grant_type")
+	issue, ok := grants[grant]
+	if !ok {
+		oauthError(w, http.StatusBadRequest, oauthUnsupportedGrantType, "grant_type must be one of client_credentials, authorization_code or refresh_token")
+		return
+	}
+	if !client.AllowsGrant(grant) {
+		oauthError(w, http.StatusBadRequest, oauthUnauthorizedClient, "client is not registered for this grant type")
+		return
+	}
+
+	tokens, err := issue(r, client)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *OAuthHandler) clientCredentialsGrant(r *http.Request, client *entities.OAuthClient) (dtos.OAuthTokenOutput, error) {
+	scope, err := client.GrantScope(r.PostForm.Get("scope"))
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	return h.issueTokens(r, client, nil, scope, entityPkg.NewID(), entityPkg.ID{})
+}
+
+func (h *OAuthHandler) authorizationCodeGrant(r *http.Request, client *entities.OAuthClient) (dtos.OAuthTokenOutput, error) {
+	code, err := h.codeDB.FindByHash(r.Context(), entities.HashAuthorizationCode(r.PostForm.Get("code")))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dtos.OAuthTokenOutput{}, entities.ErrInvalidGrant
+	}
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	redirectURI := r.PostForm.Get("redirect_uri")
+	if redirectURI == "" {
+		redirectURI, _ = client.DefaultRedirectURI()
+	}
+	if code.IsUsed() {
+		return dtos.OAuthTokenOutput{}, h.revokeCodeGrant(r, code)
+	}
+	if code.IsExpired() || code.ClientID != client.ID ||
+		code.RedirectURI != redirectURI || !code.VerifyPKCE(r.PostForm.Get("code_verifier")) {
+		return dtos.OAuthTokenOutput{}, entities.ErrInvalidGrant
+	}
+
+	tokenID, familyID := entityPkg.NewID(), entityPkg.NewID()
+	err = h.codeDB.MarkUsed(r.Context(), code.ID.String(), familyID.String(), tokenID.String())
+	if errors.Is(err, entities.ErrInvalidGrant) {
+		// lost the race against another exchange of the same code, which is a replay as well
+		code, err = h.codeDB.FindByHash(r.Context(), code.CodeHash)
+		if err != nil {
+			return dtos.OAuthTokenOutput{}, err
+		}
+		return dtos.OAuthTokenOutput{}, h.revokeCodeGrant(r, code)
+	}
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	u, err := h.delegatingUser(r, code.UserID.String())
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	return h.issueTokens(r, client, u, code.Scope, tokenID, familyID)
+}
+
+// revokeCodeGrant answers a code presented a second time, which means it leaked: the refresh token
+// family and the access token it was first exchanged for are revoked, and the request is refused with
+// entities.ErrInvalidGrant. Access tokens issued by later rotations of the family are left to expire.
+func (h *OAuthHandler) revokeCodeGrant(r *http.Request, code *entities.AuthorizationCode) error {
+	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+
+	if code.FamilyID != nil {
+		if err := h.refreshTokenDB.RevokeFamily(r.Context(), code.FamilyID.String()); err != nil {
+			return err
+		}
+	}
+	if code.TokenID != "" {
+		// the token was issued after the code was used, so it expires within jwtExpiresIn from now
+		expiresAt := time.Now().Add(time.Second * time.Duration(jwtExpiresIn))
+		if err := h.revocations.RevokeToken(r.Context(), code.TokenID, code.UserID.String(), expiresAt); err != nil {
+			return err
+		}
+	}
+
+	return entities.ErrInvalidGrant
+}
+
+func (h *OAuthHandler) refreshTokenGrant(r *http.Request, client *entities.OAuthClient) (dtos.OAuthTokenOutput, error) {
+	token, err := h.refreshTokenDB.FindByHash(r.Context(), entities.HashRefreshToken(r.PostForm.Get("refresh_token")))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dtos.OAuthTokenOutput{}, entities.ErrInvalidGrant
+	}
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+	if token.ClientID != client.ID.String() || token.IsExpired() {
+		return dtos.OAuthTokenOutput{}, entities.ErrInvalidGrant
+	}
+
+	scope, err := token.NarrowScope(r.PostForm.Get("scope"))
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	err = h.refreshTokenDB.Revoke(r.Context(), token.ID.String())
+	if errors.Is(err, entities.ErrRefreshTokenReused) {
+		// same rule as first-party rotation: a replayed token revokes the whole grant
+		if err := h.refreshTokenDB.RevokeFamily(r.Context(), token.FamilyID.String()); err != nil {
+			return dtos.OAuthTokenOutput{}, err
+		}
+		return dtos.OAuthTokenOutput{}, entities.ErrInvalidGrant
+	}
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	u, err := h.delegatingUser(r, token.UserID.String())
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	return h.issueTokens(r, client, u, scope, entityPkg.NewID(), token.FamilyID)
+}
+
+// delegatingUser loads the user a grant was issued for. Accounts that were deleted or whose email
+// is no longer verified cannot be acted for.
+func (h *OAuthHandler) delegatingUser(r *http.Request, id string) (*entities.User, error) {
+	u, err := h.userDB.FindByID(r.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !u.Verified {
+		return nil, entities.ErrInvalidGrant
+	}
+
+	return u, nil
+}
+
+// issueTokens signs an access token with the ID tokenID for client, acting for u or, when u is nil,
+// for itself. Tokens acting for a user carry the user's role and come with a refresh token in
+// familyID when the client may use the refresh_token grant.
+func (h *OAuthHandler) issueTokens(r *http.Request, client *entities.OAuthClient, u *entities.User, scope string, tokenID, familyID entityPkg.ID) (dtos.OAuthTokenOutput, error) {
+	keys := r.Context().Value("jwt").(*jwks.KeySet)
+	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+	refreshTokenExpiresIn := r.Context().Value("refreshTokenExpiresIn").(int)
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"jti":       tokenID.String(),
+		"sub":       client.ID.String(),
+		"client_id": client.ID.String(),
+		"scope":     scope,
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
+	}
+	if u != nil {
+		claims["sub"] = u.ID.String()
+		claims["role"] = string(u.Role)
+	}
+
+	_, accessToken, err := keys.Encode(claims)
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	output := dtos.OAuthTokenOutput{AccessToken: accessToken, TokenType: "Bearer", ExpiresIn: jwtExpiresIn, Scope: scope}
+	if u == nil || !client.AllowsGrant(entities.GrantRefreshToken) {
+		return output, nil
+	}
+
+	refreshToken, plain, err := entities.NewRefreshToken(u.ID, familyID, time.Second*time.Duration(refreshTokenExpiresIn))
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+	refreshToken.ClientID = client.ID.String()
+	refreshToken.Scope = scope
+
+	err = h.refreshTokenDB.Create(r.Context(), refreshToken)
+	if err != nil {
+		return dtos.OAuthTokenOutput{}, err
+	}
+
+	output.RefreshToken = plain
+	return output, nil
+}
+
+// Introspect godoc
+// @Summary OAuth2 token introspection (RFC 7662)
+// @Description Report whether an access or refresh token is active. Clients only learn about tokens
+// @Description issued to them; any other token is reported inactive.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param token formData string true "Token"
+// @Param token_type_hint formData string false "Token type hint" Enums(access_token, refresh_token)
+// @Success 200 {object} dtos.OAuthIntrospectionOutput
+// @Failure 400 {object} dtos.OAuthErrorOutput
+// @Failure 401 {object} dtos.OAuthErrorOutput
+// @Router /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if !parseOAuthForm(w, r) {
+		return
+	}
+
+	client, err := h.authenticateClient(r)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	value := r.PostForm.Get("token")
+	if value == "" {
+		oauthError(w, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	output := dtos.OAuthIntrospectionOutput{}
+	if token := h.activeAccessToken(r, client, value); token != nil {
+		scope, _ := token.Get("scope")
+		scopeString, _ := scope.(string)
+		output = dtos.OAuthIntrospectionOutput{
+			Active:    true,
+			Scope:     scopeString,
+			ClientID:  client.ID.String(),
+			Subject:   token.Subject(),
+			TokenType: "Bearer",
+			ExpiresAt: token.Expiration().Unix(),
+			IssuedAt:  token.IssuedAt().Unix(),
+			JwtID:     token.JwtID(),
+		}
+	} else if token := h.activeRefreshToken(r, client, value); token != nil {
+		output = dtos.OAuthIntrospectionOutput{
+			Active:    true,
+			Scope:     token.Scope,
+			ClientID:  token.ClientID,
+			Subject:   token.UserID.String(),
+			ExpiresAt: token.ExpiresAt.Unix(),
+			IssuedAt:  token.CreatedAt.Unix(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// Revoke godoc
+// @Summary OAuth2 token revocation (RFC 7009)
+// @Description Revoke an access token or a refresh token, together with every token rotated from the
+// @Description same grant. Unknown tokens and tokens of other clients are ignored.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Param token formData string true "Token"
+// @Param token_type_hint formData string false "Token type hint" Enums(access_token, refresh_token)
+// @Success 200
+// @Failure 400 {object} dtos.OAuthErrorOutput
+// @Failure 401 {object} dtos.OAuthErrorOutput
+// @Router /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if !parseOAuthForm(w, r) {
+		return
+	}
+
+	client, err := h.authenticateClient(r)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	value := r.PostForm.Get("token")
+	if value == "" {
+		oauthError(w, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	if token := h.activeAccessToken(r, client, value); token != nil {
+		err = h.revocations.RevokeToken(r.Context(), token.JwtID(), token.Subject(), token.Expiration())
+	} else if token := h.activeRefreshToken(r, client, value); token != nil {
+		err = h.refreshTokenDB.RevokeFamily(r.Context(), token.FamilyID.String())
+	}
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// activeAccessToken returns value as a verified, unexpired and unrevoked access token issued to
+// client, or nil.
+func (h *OAuthHandler) activeAccessToken(r *http.Request, client *entities.OAuthClient, value string) jwt.Token {
+	keys := r.Context().Value("jwt").(*jwks.KeySet)
+
+	token, err := keys.Decode(value)
+	if err != nil || jwt.Validate(token) != nil {
+		return nil
+	}
+	if clientID, _ := token.Get("client_id"); clientID != client.ID.String() {
+		return nil
+	}
+
+	revoked, err := h.revocations.IsRevoked(r.Context(), token.JwtID(), token.Subject(), token.IssuedAt())
+	if err != nil || revoked {
+		return nil
+	}
+
+	return token
+}
+
+// activeRefreshToken returns the unexpired and unrevoked refresh token issued to client for value,
+// or nil.
+func (h *OAuthHandler) activeRefreshToken(r *http.Request, client *entities.OAuthClient, value string) *entities.RefreshToken {
+	token, err := h.refreshTokenDB.FindByHash(r.Context(), entities.HashRefreshToken(value))
+	if err != nil || token.ClientID != client.ID.String() || token.IsExpired() || token.IsRevoked() {
+		return nil
+	}
+
+	return token
+}
+
+// authenticateClient identifies the client from HTTP Basic credentials or, failing that, the
+// client_id and client_secret form fields. Using both is refused.
+func (h *OAuthHandler) authenticateClient(r *http.Request) (*entities.OAuthClient, error) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		if r.PostForm.Get("client_secret") != "" {
+			return nil, entities.ErrInvalidClient
+		}
+		// RFC 6749 section 2.3.1 form-encodes the credentials before Basic encoding them
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id == "" {
+		return nil, entities.ErrInvalidClient
+	}
+
+	client, err := h.clientDB.FindByID(r.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrInvalidClient
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !client.Authenticate(secret) {
+		return nil, entities.ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// parseOAuthForm reads the form encoded body of an OAuth2 endpoint, answering with invalid_request
+// when it cannot.
+func parseOAuthForm(w http.ResponseWriter, r *http.Request) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		oauthError(w, http.StatusBadRequest, oauthInvalidRequest, "body must be application/x-www-form-urlencoded")
+		return false
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, oauthInvalidRequest, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// writeOAuthError maps domain errors to the error responses of RFC 6749 section 5.2.
+func writeOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, entities.ErrInvalidClient):
+		if _, _, basic := r.BasicAuth(); basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		oauthError(w, http.StatusUnauthorized, oauthInvalidClient, err.Error())
+	case errors.Is(err, entities.ErrInvalidGrant):
+		oauthError(w, http.StatusBadRequest, oauthInvalidGrant, err.Error())
+	case errors.Is(err, entities.ErrInvalidScope):
+		oauthError(w, http.StatusBadRequest, oauthInvalidScope, err.Error())
+	default:
+		oauthError(w, http.StatusInternalServerError, oauthServerError, "an unexpected error occurred")
+	}
+}
+
+func oauthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(dtos.OAuthErrorOutput{Error: code, ErrorDescription: description})
+}
+
+func clientOutput(client *entities.OAuthClient, secret string) dtos.OAuthClientOutput {
+	return dtos.OAuthClientOutput{
+		ClientID:     client.ID.String(),
+		ClientSecret: secret,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		GrantTypes:   strings.Fields(client.GrantTypes),
+		Scope:        client.Scope,
+		Public:       !client.IsConfidential(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizationCodeReuseRevokesTokens(t *testing.T) {
+	db, err := database.NewConnection(database.Config{Driver: database.DriverSQLite, Name: database.SQLiteMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.NewMigrator(db, migrations.All()).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	userDB := database.NewUser(db)
+	user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+	user.Verified = true
+	assert.NoError(t, userDB.Create(context.Background(), user))
+
+	clientDB := database.NewOAuthClient(db)
+	client, _, err := entities.NewOAuthClient("Shop", []string{"https://shop.example/callback"},
+		[]string{entities.GrantAuthorizationCode, entities.GrantRefreshToken}, nil, false)
+	assert.NoError(t, err)
+	assert.NoError(t, clientDB.Create(context.Background(), client))
+
+	codeDB := database.NewAuthorizationCode(db)
+	code, plain, err := entities.NewAuthorizationCode(client.ID, user.ID, "https://shop.example/callback", "products:read",
+		"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, codeDB.Create(context.Background(), code))
+
+	keys, _ := jwks.NewHMAC("HS256", []byte("secret"))
+	revocations := revocation.NewMemory()
+	oauthHandler := NewOAuthHandler(clientDB, codeDB, userDB, database.NewRefreshToken(db), revocations)
+
+	r := chi.NewRouter()
+	r.Use(middleware.WithValue("jwt", keys))
+	r.Use(middleware.WithValue("jwtExpiresIn", 300))
+	r.Use(middleware.WithValue("refreshTokenExpiresIn", 3600))
+	r.Post("/oauth/token", oauthHandler.Token)
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	exchange := url.Values{
+		"grant_type":    {entities.GrantAuthorizationCode},
+		"client_id":     {client.ID.String()},
+		"code":          {plain},
+		"redirect_uri":  {"https://shop.example/callback"},
+		"code_verifier": {"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"},
+	}
+
+	w := post(exchange)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens dtos.OAuthTokenOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&tokens))
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// the replayed code is refused and takes the first exchange's tokens down with it
+	w = post(exchange)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_grant")
+
+	accessToken, err := keys.Decode(tokens.AccessToken)
+	assert.NoError(t, err)
+	revoked, err := revocations.IsRevoked(context.Background(), accessToken.JwtID(), accessToken.Subject(), accessToken.IssuedAt())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	w = post(url.Values{
+		"grant_type":    {entities.GrantRefreshToken},
+		"client_id":     {client.ID.String()},
+		"refresh_token": {tokens.RefreshToken},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_grant")
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+)
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in</title></head>
+<body>
+<h1>Sign in</h1>
+{{if .Error}}<p>{{.Error}}</p>
+{{end}}{{if .ReturnTo}}<form method="post" action="/oauth/login">
+<input type="hidden" name="return_to" value="{{.ReturnTo}}">
+<input type="hidden" name="csrf_token" value="{{.CSRF}}">
+<label>Email <input type="email" name="email" value="{{.Email}}" required></label>
+<label>Password <input type="password" name="password" required></label>
+{{if .NeedCode}}<label>Authenticator or recovery code <input type="text" name="code" autocomplete="one-time-code"></label>
+{{end}}<button type="submit">Sign in</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+type loginView struct {
+	ReturnTo string
+	CSRF     string
+	Email    string
+	NeedCode bool
+	Error    string
+}
+
+// Login page godoc
+// @Summary Sign in to authorize an OAuth2 client
+// @Description The authorization endpoint sends browsers without a session here. Signing in sets a
+// @Description cookie for /oauth and returns to return_to, which must be an /oauth/authorize URL. The
+// @Description form carries a CSRF token that is checked against a cookie set with the page.
+// @Tags oauth
+// @Produce  html
+// @Param return_to query string true "Authorization request to return to"
+// @Success 200
+// @Failure 400
+// @Router /oauth/login [get]
+func (h *UserHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	returnTo := r.URL.Query().Get("return_to")
+	if !isAuthorizeURL(returnTo) {
+		renderLogin(w, http.StatusBadRequest, loginView{Error: "Start again from the application."})
+		return
+	}
+
+	csrf, err := consentCookies(r).IssueLoginCSRF(w)
+	if err != nil {
+		renderLogin(w, http.StatusInternalServerError, loginView{Error: "Something went wrong. Try again later."})
+		return
+	}
+
+	renderLogin(w, http.StatusOK, loginView{ReturnTo: returnTo, CSRF: csrf})
+}
+
+// Login godoc
+// @Summary Submit the OAuth2 sign-in page
+// @Description Check the credentials, and the two-factor code for accounts that have it, then redirect
+// @Description to return_to with the consent cookie set. Failures count towards the login lockout. A
+// @Description form without the CSRF token of the page that rendered it is refused with 403.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  html
+// @Success 303
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 429
+// @Router /oauth/login [post]
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	err := r.ParseForm()
+	if err != nil {
+		renderLogin(w, http.StatusBadRequest, loginView{Error: "The form could not be read."})
+		return
+	}
+
+	returnTo := r.PostForm.Get("return_to")
+	if !isAuthorizeURL(returnTo) {
+		renderLogin(w, http.StatusBadRequest, loginView{Error: "Start again from the application."})
+		return
+	}
+
+	email := entities.NormalizeEmail(r.PostForm.Get("email"))
+	view := loginView{ReturnTo: returnTo, CSRF: r.PostForm.Get(session.LoginCSRFField), Email: email}
+	ip := clientIP(r)
+
+	// a form posted from another site must not sign the browser in to an account of its choosing
+	if !consentCookies(r).CheckLoginCSRF(r) {
+		view.CSRF, err = consentCookies(r).IssueLoginCSRF(w)
+		if err != nil {
+			view.Error = "Something went wrong. Try again later."
+			renderLogin(w, http.StatusInternalServerError, view)
+			return
+		}
+		view.Error = "The sign-in form expired. Sign in again."
+		renderLogin(w, http.StatusForbidden, view)
+		return
+	}
+
+	u, wait, err := h.checkPassword(r.Context(), email, r.PostForm.Get("password"), ip)
+	if wait > 0 {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		view.Error = fmt.Sprintf("Too many failed sign-ins. Try again in %d seconds.", retryAfter)
+		renderLogin(w, http.StatusTooManyRequests, view)
+		return
+	}
+	if errors.Is(err, entities.ErrInvalidCredentials) {
+		view.Error = "The email address or password is incorrect."
+		renderLogin(w, http.StatusUnauthorized, view)
+		return
+	}
+	if err != nil {
+		view.Error = "Something went wrong. Try again later."
+		renderLogin(w, http.StatusInternalServerError, view)
+		return
+	}
+	if !u.Verified {
+		view.Error = "Verify your email address before authorizing applications."
+		renderLogin(w, http.StatusForbidden, view)
+		return
+	}
+
+	if u.TOTPEnabled {
+		view.NeedCode = true
+		code := strings.TrimSpace(r.PostForm.Get("code"))
+		if code == "" {
+			view.Error = "Enter the code from your authenticator app, or a recovery code."
+			renderLogin(w, http.StatusUnauthorized, view)
+			return
+		}
+
+		err = h.checkSecondFactor(r, u, code)
+		if errors.Is(err, entities.ErrInvalidTwoFactorCode) {
+			h.loginGuard.Fail(r.Context(), email, ip)
+			view.Error = "The code is incorrect."
+			renderLogin(w, http.StatusUnauthorized, view)
+			return
+		}
+		if err != nil {
+			view.Error = "Something went wrong. Try again later."
+			renderLogin(w, http.StatusInternalServerError, view)
+			return
+		}
+	}
+	h.loginGuard.Succeed(r.Context(), email)
+
+	accessToken, err := signAccessToken(r, u)
+	if err != nil {
+		view.Error = "Something went wrong. Try again later."
+		renderLogin(w, http.StatusInternalServerError, view)
+		return
+	}
+
+	consentCookies(r).IssueConsent(w, accessToken, time.Second*time.Duration(jwtExpiresIn))
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
+
+// isAuthorizeURL reports whether returnTo is a path to the authorization endpoint of this server, so
+// that the sign-in page cannot be used to redirect anywhere else.
+func isAuthorizeURL(returnTo string) bool {
+	u, err := url.Parse(returnTo)
+	return err == nil && u.Scheme == "" && u.Host == "" && u.Path == "/oauth/authorize"
+}
+
+func renderLogin(w http.ResponseWriter, status int, view loginView) {
+	renderPage(w, status, loginPage, view)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/audit"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/lockout"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// newConsentRouter serves /oauth/login and /oauth/authorize the way cmd/server does, with a verified
+// user caio@caio.com and a public client registered for https://shop.example/callback.
+func newConsentRouter(t *testing.T) (http.Handler, *entities.OAuthClient) {
+	db, err := database.NewConnection(database.Config{Driver: database.DriverSQLite, Name: database.SQLiteMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.NewMigrator(db, migrations.All()).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	userDB := database.NewUser(db)
+	user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+	user.Verified = true
+	assert.NoError(t, userDB.Create(context.Background(), user))
+
+	clientDB := database.NewOAuthClient(db)
+	client, _, err := entities.NewOAuthClient("Shop", []string{"https://shop.example/callback"},
+		[]string{entities.GrantAuthorizationCode}, nil, false)
+	assert.NoError(t, err)
+	assert.NoError(t, clientDB.Create(context.Background(), client))
+
+	keys, _ := jwks.NewHMAC("HS256", []byte("secret"))
+	revocations := revocation.NewMemory()
+	consentCookies := session.Consent("", true)
+	guard := lockout.NewGuard(lockout.Policy{MaxFailures: 5, Lockout: time.Minute, Window: time.Minute},
+		lockout.Policy{MaxFailures: 50, Lockout: time.Minute, Window: time.Minute}, audit.NewWriter(io.Discard))
+
+	userHandler := NewUserHandler(userDB, database.NewRefreshToken(db), database.NewAPIKey(db), database.NewEmailVerificationToken(db),
+		database.NewRecoveryCode(db), database.NewTwoFactorChallenge(db), database.NewTransactionManager(db), revocations, nil, guard)
+	oauthHandler := NewOAuthHandler(clientDB, database.NewAuthorizationCode(db), userDB, database.NewRefreshToken(db), revocations)
+
+	r := chi.NewRouter()
+	r.Use(middleware.WithValue("jwt", keys))
+	r.Use(middleware.WithValue("jwtExpiresIn", 300))
+	r.Use(middleware.WithValue("oauthCodeExpiresIn", 60))
+	r.Use(middleware.WithValue("consentCookies", consentCookies))
+	r.Get("/oauth/login", userHandler.LoginPage)
+	r.Post("/oauth/login", userHandler.Login)
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares.Verifier(keys, consentCookies))
+		r.Use(middlewares.LoginRedirect("/oauth/login", revocations))
+		r.Use(middlewares.FirstPartyOnly)
+
+		r.Get("/oauth/authorize", oauthHandler.Authorize)
+	})
+
+	return r, client
+}
+
+func TestAuthorizeWithBrowserCookie(t *testing.T) {
+	router, client := newConsentRouter(t)
+
+	authorizeURL := "/oauth/authorize?" + url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID.String()},
+		"redirect_uri":          {"https://shop.example/callback"},
+		"state":                 {"xyz"},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {entities.PKCEMethodS256},
+	}.Encode()
+
+	// a browser arriving from the client without a session is sent to sign in
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, authorizeURL, nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	login, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/oauth/login", login.Path)
+	assert.Equal(t, authorizeURL, login.Query().Get("return_to"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, login.String(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	csrf := loginCSRF(t, w)
+
+	form := url.Values{"email": {"caio@caio.com"}, "password": {"wrong-password"}, "return_to": {authorizeURL},
+		session.LoginCSRFField: {csrf.Value}}
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		return postLogin(router, form, csrf)
+	}
+	w = post(form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+
+	form.Set("password", "12345678")
+	w = post(form)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, authorizeURL, w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+	// the cookie alone, with no Authorization header, opens the consent page
+	r := httptest.NewRequest(http.MethodGet, authorizeURL, nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Shop wants to access your account")
+	assert.Contains(t, w.Body.String(), "caio@caio.com")
+}
+
+// loginCSRF returns the CSRF cookie the sign-in page set, after checking that its form carries it.
+func loginCSRF(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	cookies := w.Result().Cookies()
+	if !assert.Len(t, cookies, 1) {
+		t.FailNow()
+	}
+	assert.Contains(t, w.Body.String(), `name="csrf_token" value="`+cookies[0].Value+`"`)
+	return cookies[0]
+}
+
+func postLogin(router http.Handler, form url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/oauth/login", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestLoginRequiresCSRFToken(t *testing.T) {
+	router, _ := newConsentRouter(t)
+	returnTo := "/oauth/authorize?response_type=code"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/login?"+url.Values{"return_to": {returnTo}}.Encode(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	csrf := loginCSRF(t, w)
+
+	form := url.Values{"email": {"caio@caio.com"}, "password": {"12345678"}, "return_to": {returnTo}}
+	cases := []struct {
+		name   string
+		field  string
+		cookie *http.Cookie
+	}{
+		{"no token", "", csrf},
+		{"no cookie", csrf.Value, nil},
+		{"forged token", "forged", csrf},
+	}
+	for _, c := range cases {
+		form.Set(session.LoginCSRFField, c.field)
+		w = postLogin(router, form, c.cookie)
+		assert.Equal(t, http.StatusForbidden, w.Code, c.name)
+
+		// the page comes back with a fresh token instead of the consent cookie
+		fresh := loginCSRF(t, w)
+		assert.NotEqual(t, csrf.Value, fresh.Value, c.name)
+	}
+
+	form.Set(session.LoginCSRFField, csrf.Value)
+	w = postLogin(router, form, csrf)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+}
+
+func TestLoginRefusesOtherReturnTo(t *testing.T) {
+	router, _ := newConsentRouter(t)
+
+	for _, returnTo := range []string{"", "https://evil.example/oauth/authorize", "//evil.example/oauth/authorize", "/users/me"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/login?"+url.Values{"return_to": {returnTo}}.Encode(), nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, returnTo)
+		assert.NotContains(t, w.Body.String(), "<form", returnTo)
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/caiocp/go-api/pkg/query"
+)
+
+// paginate sets X-Total-Count and, for a paged list, a Link header (RFC 8288) pointing at the first,
+// previous, next and last pages, leaving the body to the list itself.
+func paginate(w http.ResponseWriter, r *http.Request, spec query.Spec, total int64) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if spec.Limit == 0 {
+		return
+	}
+
+	pages := spec.TotalPages(total)
+
+	link := func(page int, rel string) string {
+		values := r.URL.Query()
+		values.Set("page", strconv.Itoa(page))
+		values.Set("limit", strconv.Itoa(spec.Limit))
+		u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+		return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if spec.Page > 1 {
+		links = append(links, link(spec.Page-1, "prev"))
+	}
+	if spec.Page < pages {
+		links = append(links, link(spec.Page+1, "next"))
+	}
+	links = append(links, link(pages, "last"))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
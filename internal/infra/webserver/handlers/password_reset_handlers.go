@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/mail"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"gorm.io/gorm"
+)
+
+type PasswordResetHandler struct {
+	userDB         database.UserInterface
+	resetTokenDB   database.PasswordResetTokenInterface
+	refreshTokenDB database.RefreshTokenInterface
+	transactions   database.TransactionManagerInterface
+	revocations    revocation.Store
+	mailer         mail.Mailer
+}
+
+func NewPasswordResetHandler(userDB database.UserInterface, resetTokenDB database.PasswordResetTokenInterface,
+	refreshTokenDB database.RefreshTokenInterface, transactions database.TransactionManagerInterface,
+	revocations revocation.Store, mailer mail.Mailer) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		userDB:         userDB,
+		resetTokenDB:   resetTokenDB,
+		refreshTokenDB: refreshTokenDB,
+		transactions:   transactions,
+		revocations:    revocations,
+		mailer:         mailer,
+	}
+}
+
+// Request password reset godoc
+// @Summary Request a password reset
+// @Description Email a single-use token for setting a new password. The response is the same whether
+// @Description or not an account uses the address.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.PasswordResetInput true "Account email"
+// @Success 202
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/password_reset [post]
+func (h *PasswordResetHandler) RequestReset(w http.ResponseWriter, r *http.Request) {
+	passwordResetExpiresIn := r.Context().Value("passwordResetExpiresIn").(int)
+
+	var input dtos.PasswordResetInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByEmail(r.Context(), input.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	ttl := time.Second * time.Duration(passwordResetExpiresIn)
+	token, plain, err := entities.NewPasswordResetToken(u.ID, ttl)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.resetTokenDB.Create(r.Context(), token)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	sendMail(h.mailer, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+			"Send this token to POST /users/password_reset/confirm within %s to choose a new password:\n\n%s\n\n"+
+			"If it was not you, ignore this message and your password stays the same.", ttl, plain),
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Confirm password reset godoc
+// @Summary Confirm a password reset
+// @Description Set a new password with a token from the reset email. The token works once, and every
+// @Description session of the account is signed out. The password has to be PASSWORD_MIN_LENGTH long and
+// @Description not on the breached password list.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.ConfirmPasswordResetInput true "Reset token and new password"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/password_reset/confirm [post]
+func (h *PasswordResetHandler) ConfirmReset(w http.ResponseWriter, r *http.Request) {
+	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+
+	var input dtos.ConfirmPasswordResetInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	token, err := h.resetTokenDB.FindByHash(r.Context(), entities.HashPasswordResetToken(input.Token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidPasswordResetToken)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if token.IsExpired() || token.IsUsed() {
+		problem.Error(w, r, entities.ErrInvalidPasswordResetToken)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), token.UserID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidPasswordResetToken)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// a password the policy refuses leaves the token usable for another try
+	err = u.SetPassword(input.NewPassword)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// a failed password write must not burn the token
+	err = h.transactions.WithinTransaction(r.Context(), func(ctx context.Context, repos database.Repositories) error {
+		if err := repos.PasswordResetTokens.MarkUsed(ctx, token.ID.String()); err != nil {
+			return err
+		}
+		if err := repos.Users.UpdatePassword(ctx, u.ID.String(), u.Password); err != nil {
+			return err
+		}
+		return repos.PasswordResetTokens.InvalidateUser(ctx, u.ID.String())
+	})
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// whoever knew the old password may still hold tokens, sign every session out
+	now := time.Now()
+	err = h.revocations.RevokeSubject(r.Context(), u.ID.String(), now, now.Add(time.Second*time.Duration(jwtExpiresIn)))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.refreshTokenDB.RevokeUser(r.Context(), u.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,95 +1,183 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/caiocp/go-api/internal/dtos"
 	"github.com/caiocp/go-api/internal/entities"
 	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
 	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/caiocp/go-api/pkg/jsonpatch"
+	"github.com/caiocp/go-api/pkg/query"
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 type ProductHandler struct {
 	ProductDB database.ProductInterface
+	UserDB    database.UserInterface
 }
 
-func NewProductHandler(db database.ProductInterface) *ProductHandler {
+func NewProductHandler(db database.ProductInterface, userDB database.UserInterface) *ProductHandler {
 	return &ProductHandler{
 		ProductDB: db,
+		UserDB:    userDB,
 	}
 }
 
 // Create Product godoc
 // @Summary Create a new product
-// @Description Create a new product
+// @Description Create a new product owned by the caller. Tokens an OAuth2 client obtained for itself
+// @Description act for no user and cannot create products.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param product body dtos.CreateProductInput true "Product request"
 // @Success 201
-// @Failure 500
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /products [post]
 // @Security ApiKeyAuth
+// @Security PersonalAPIKey
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.UserFromContext(r)
+	if user == "" {
+		problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+			"products need an owner; tokens an OAuth2 client obtained for itself cannot create them"))
+		return
+	}
+
 	var product dtos.CreateProductInput
-	err := json.NewDecoder(r.Body).Decode(&product)
+	err := decodeJSON(w, r, &product)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
 	p, err := entities.NewProduct(product.Name, product.Price)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
-	err = h.ProductDB.Create(p)
+	ownerID, err := entityPkg.ParseID(user)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, err)
+		return
+	}
+	p.OwnerID = &ownerID
+
+	err = h.ProductDB.Create(r.Context(), p)
+	if err != nil {
+		problem.Error(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+// productQuery is what GET /products accepts besides owner.
+var productQuery = query.NewBuilder().
+	OptionalPaging().
+	Filter("name_contains", "name", query.Contains, query.String).
+	Filter("name_prefix", "name", query.Prefix, query.String).
+	Filter("price_min", "price", query.Gte, query.Number).
+	Filter("price_max", "price", query.Lte, query.Number).
+	Filter("created_from", "created_at", query.Gte, query.Time).
+	Filter("created_to", "created_at", query.Lte, query.Time).
+	SortBy("name", "name").
+	SortBy("price", "price").
+	SortBy("created_at", "created_at").
+	DefaultSort("created_at")
+
+// trashQuery is what GET /products/trash accepts.
+var trashQuery = query.NewBuilder().
+	OptionalPaging().
+	SortBy("deleted_at", "deleted_at").
+	SortBy("name", "name").
+	SortBy("price", "price").
+	DefaultSort("-deleted_at")
+
 // Get Products godoc
 // @Summary Get all products
-// @Description Get all products
+// @Description Get products with optional filters, every one of them unless page or limit is given.
+// @Description Names match case-insensitively; a created_to date without a time includes the whole
+// @Description day. The X-Total-Count header carries the total and, for a page, the Link header the
+// @Description neighbouring pages.
 // @Tags products
 // @Accept  json
 // @Produce  json
-// @Param page query string false "Page number"
-// @Param limit query string false "Limit number"
-// @Param sort query string false "Sort by field" default(asc) Enums(asc, desc)
+// @Param page query int false "Page number, 10 products per page unless limit is given"
+// @Param limit query int false "Page size, at most 100"
+// @Param sort query string false "Comma separated fields among name, price and created_at; a leading - sorts descending" default(created_at)
+// @Param owner query string false "Only products of this owner; me for the caller's own, which tokens acting for no user cannot use"
+// @Param name_contains query string false "Only products whose name contains this text"
+// @Param name_prefix query string false "Only products whose name starts with this text"
+// @Param price_min query number false "Lowest price"
+// @Param price_max query number false "Highest price"
+// @Param created_from query string false "Created at or after this date or RFC 3339 time"
+// @Param created_to query string false "Created at or before this date or RFC 3339 time"
 // @Success 200 {array} entities.Product
-// @Failure 404 {object} Error
-// @Failure 500 {object} Error
+// @Header 200 {integer} X-Total-Count "Number of matching products"
+// @Header 200 {string} Link "First, previous, next and last pages"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /products [get]
 // @Security ApiKeyAuth
+// @Security PersonalAPIKey
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	page := r.URL.Query().Get("page")
-	limit := r.URL.Query().Get("limit")
-	sort := r.URL.Query().Get("sort")
+	values := r.URL.Query()
+	// sort=asc and sort=desc predate sorting by field and order by creation time
+	switch values.Get("sort") {
+	case "asc":
+		values.Set("sort", "created_at")
+	case "desc":
+		values.Set("sort", "-created_at")
+	}
 
-	pageInt, err := strconv.Atoi(page)
+	spec, err := productQuery.Parse(values)
 	if err != nil {
-		pageInt = 0
+		problem.Error(w, r, err)
+		return
 	}
-	limitInt, err := strconv.Atoi(limit)
-	if err != nil {
-		limitInt = 10
+
+	owner := values.Get("owner")
+	if owner == "me" {
+		owner = middlewares.UserFromContext(r)
+		if owner == "" {
+			problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidParam,
+				"owner=me needs a token that acts for a user"))
+			return
+		}
+	} else if _, err := entityPkg.ParseID(owner); owner != "" && err != nil {
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidParam, "owner must be me or a user id"))
+		return
+	}
+	if owner != "" {
+		spec.Filters = append(spec.Filters, query.Filter{Field: "owner_id", Op: query.Eq, Value: owner})
 	}
 
-	products, err := h.ProductDB.FindAll(pageInt, limitInt, sort)
+	products, total, err := h.ProductDB.FindAll(r.Context(), spec)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, err)
 		return
 	}
 
+	paginate(w, r, spec, total)
+	if products == nil {
+		products = []entities.Product{}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(products)
@@ -97,27 +185,38 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 
 // Get Product godoc
 // @Summary Get a product
-// @Description Get a product
+// @Description Get a product. The ETag header identifies its version, for If-Match on changes and
+// @Description If-None-Match here.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Product ID" Format(uuid)
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} entities.Product
-// @Failure 404
-// @Failure 500
+// @Success 304
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /products/{id} [get]
 // @Security ApiKeyAuth
+// @Security PersonalAPIKey
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidParam, "id is required"))
 		return
 	}
 
-	product, err := h.ProductDB.FindByID(id)
+	product, err := h.ProductDB.FindByID(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", productETag(product))
+	if matchesETag(r.Header.Get("If-None-Match"), product, true) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -127,85 +226,452 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 }
 
 // Update Product godoc
-// @Summary Update a product
-// @Description Update a product
+// @Summary Replace a product
+// @Description Replace the name and price of a product. Both are required; the id, owner and creation
+// @Description time are kept. Only the owner may, unless the caller has the products:manage permission.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Product ID" Format(uuid)
+// @Param If-Match header string false "ETag of the version being changed; required when PRODUCTS_REQUIRE_IF_MATCH is on"
 // @Param request body dtos.CreateProductInput true "Product request"
-// @Success 200
-// @Failure 404
-// @Failure 500
+// @Success 200 {object} entities.Product
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /products/{id} [put]
 // @Security ApiKeyAuth
+// @Security PersonalAPIKey
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+	var input dtos.CreateProductInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	product, ok := h.changeableProduct(w, r, h.ProductDB.FindByID)
+	if !ok || !checkIfMatch(w, r, product) {
+		return
+	}
+
+	h.saveProduct(w, r, product, input)
+}
+
+// Patch Product godoc
+// @Summary Partially update a product
+// @Description Change a product with a JSON Merge Patch (RFC 7396, application/merge-patch+json) or a
+// @Description JSON Patch (RFC 6902, application/json-patch+json) applied to its name and price. The
+// @Description result is validated like a PUT body. Only the owner may, unless the caller has the
+// @Description products:manage permission.
+// @Tags products
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  json
+// @Param id path string true "Product ID" Format(uuid)
+// @Param If-Match header string false "ETag of the version being changed; required when PRODUCTS_REQUIRE_IF_MATCH is on"
+// @Param request body object true "Patch document"
+// @Success 200 {object} entities.Product
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /products/{id} [patch]
+// @Security ApiKeyAuth
+// @Security PersonalAPIKey
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	apply, ok := patchers[mediaType]
+	if !ok {
+		w.Header().Set("Accept-Patch", acceptPatch)
+		problem.Write(w, r, problem.New(http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+			"content type must be one of "+acceptPatch))
 		return
 	}
 
-	var product entities.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
+	patch, err := readBody(w, r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
-	product.ID, err = entityPkg.ParseID(id)
+	product, ok := h.changeableProduct(w, r, h.ProductDB.FindByID)
+	if !ok || !checkIfMatch(w, r, product) {
+		return
+	}
+
+	doc, err := json.Marshal(dtos.CreateProductInput{Name: product.Name, Price: product.Price})
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
-	_, err = h.ProductDB.FindByID(id)
+	patched, err := apply(doc, patch)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		problem.Error(w, r, patchProblem(err))
 		return
 	}
 
-	err = h.ProductDB.Update(&product)
+	var input dtos.CreateProductInput
+	err = decode(bytes.NewReader(patched), &input)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, err)
 		return
 	}
 
+	h.saveProduct(w, r, product, input)
+}
+
+// Get Trash godoc
+// @Summary List deleted products
+// @Description List the products in the trash, most recently deleted first, every one of them unless
+// @Description page or limit is given. Callers see their own unless they have the products:manage
+// @Description permission. Trashed products are purged after PRODUCT_TRASH_RETENTION. The X-Total-Count
+// @Description header carries the total and, for a page, the Link header the neighbouring pages.
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Page number, 10 products per page unless limit is given"
+// @Param limit query int false "Page size, at most 100"
+// @Param sort query string false "Comma separated fields among deleted_at, name and price; a leading - sorts descending" default(-deleted_at)
+// @Success 200 {array} entities.Product
+// @Header 200 {integer} X-Total-Count "Number of products in the trash"
+// @Header 200 {string} Link "First, previous, next and last pages"
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /products/trash [get]
+// @Security ApiKeyAuth
+// @Security PersonalAPIKey
+func (h *ProductHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	spec, err := trashQuery.Parse(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if !middlewares.HasPermission(r, entities.PermissionManageProducts) {
+		owner := middlewares.UserFromContext(r)
+		if owner == "" {
+			problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+				"listing the trash of every owner requires the "+string(entities.PermissionManageProducts)+" permission"))
+			return
+		}
+		spec.Filters = append(spec.Filters, query.Filter{Field: "owner_id", Op: query.Eq, Value: owner})
+	}
+
+	products, total, err := h.ProductDB.FindDeleted(r.Context(), spec)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	paginate(w, r, spec, total)
+	if products == nil {
+		products = []entities.Product{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(products)
 }
 
 // Delete Product godoc
 // @Summary Delete a product
-// @Description Delete a product
+// @Description Move a product to the trash, from where it can be restored until it is purged. Only its
+// @Description owner may, unless the caller has the products:manage permission. With hard=true the
+// @Description product, in the trash or not, is removed for good; that requires products:manage.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Product ID" Format(uuid)
+// @Param hard query bool false "Remove the product permanently"
+// @Param If-Match header string false "ETag of the version being changed; required when PRODUCTS_REQUIRE_IF_MATCH is on"
 // @Success 200
-// @Failure 404
-// @Failure 500
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /products/{id} [delete]
 // @Security ApiKeyAuth
+// @Security PersonalAPIKey
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+	hard := false
+	if value := r.URL.Query().Get("hard"); value != "" {
+		var err error
+		hard, err = strconv.ParseBool(value)
+		if err != nil {
+			problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidParam, "hard must be true or false"))
+			return
+		}
+	}
 
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if hard {
+		h.purgeProduct(w, r)
+		return
+	}
+
+	product, ok := h.changeableProduct(w, r, h.ProductDB.FindByID)
+	if !ok || !checkIfMatch(w, r, product) {
+		return
+	}
+
+	err := h.ProductDB.Delete(r.Context(), product.ID.String(), product.Version)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// purgeProduct removes the product named in the URL permanently, whether or not it is in the trash.
+func (h *ProductHandler) purgeProduct(w http.ResponseWriter, r *http.Request) {
+	if !middlewares.HasPermission(r, entities.PermissionManageProducts) {
+		problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+			"deleting a product permanently requires the "+string(entities.PermissionManageProducts)+" permission"))
+		return
+	}
+
+	product, ok := h.changeableProduct(w, r, h.findAnyProduct)
+	if !ok || !checkIfMatch(w, r, product) {
+		return
+	}
+
+	err := h.ProductDB.Purge(r.Context(), product.ID.String(), product.Version)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Restore Product godoc
+// @Summary Restore a deleted product
+// @Description Take a product out of the trash. Only its owner may, unless the caller has the
+// @Description products:manage permission.
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Product ID" Format(uuid)
+// @Param If-Match header string false "ETag of the deleted version; required when PRODUCTS_REQUIRE_IF_MATCH is on"
+// @Success 200 {object} entities.Product
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /products/{id}/restore [post]
+// @Security ApiKeyAuth
+// @Security PersonalAPIKey
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.changeableProduct(w, r, h.ProductDB.FindDeletedByID)
+	if !ok || !checkIfMatch(w, r, product) {
+		return
+	}
+
+	err := h.ProductDB.Restore(r.Context(), product.ID.String(), product.Version)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	product, err = h.ProductDB.FindByID(r.Context(), product.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", productETag(product))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// Transfer Product godoc
+// @Summary Transfer a product to another owner
+// @Description Make another user the owner of a product. Requires the products:manage permission.
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Product ID" Format(uuid)
+// @Param If-Match header string false "ETag of the version being changed; required when PRODUCTS_REQUIRE_IF_MATCH is on"
+// @Param request body dtos.TransferProductInput true "New owner"
+// @Success 200
+// @Header 200 {string} ETag "Version of the transferred product"
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /products/{id}/owner [put]
+// @Security ApiKeyAuth
+// @Security PersonalAPIKey
+func (h *ProductHandler) TransferProduct(w http.ResponseWriter, r *http.Request) {
+	var input dtos.TransferProductInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	product, ok := h.changeableProduct(w, r, h.ProductDB.FindByID)
+	if !ok || !checkIfMatch(w, r, product) {
 		return
 	}
 
-	_, err := h.ProductDB.FindByID(id)
+	owner, err := h.UserDB.FindByID(r.Context(), input.OwnerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidOwner)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		problem.Error(w, r, err)
 		return
 	}
 
-	err = h.ProductDB.Delete(id)
+	err = h.ProductDB.UpdateOwner(r.Context(), product.ID.String(), product.Version, owner.ID.String())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, err)
 		return
 	}
 
+	product.Version++
+	w.Header().Set("ETag", productETag(product))
 	w.WriteHeader(http.StatusOK)
 }
+
+// changeableProduct loads the product named in the URL with find, answering the request itself when it
+// does not exist or the caller may not change it.
+func (h *ProductHandler) changeableProduct(w http.ResponseWriter, r *http.Request, find productFinder) (*entities.Product, bool) {
+	id, err := entityPkg.ParseID(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Error(w, r, entities.ErrInvalidID)
+		return nil, false
+	}
+
+	product, err := find(r.Context(), id.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return nil, false
+	}
+	if !canChange(r, product) {
+		problem.Write(w, r, errNotOwner)
+		return nil, false
+	}
+
+	return product, true
+}
+
+type productFinder func(ctx context.Context, id string) (*entities.Product, error)
+
+// findAnyProduct finds a product whether or not it is in the trash.
+func (h *ProductHandler) findAnyProduct(ctx context.Context, id string) (*entities.Product, error) {
+	product, err := h.ProductDB.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return h.ProductDB.FindDeletedByID(ctx, id)
+	}
+
+	return product, err
+}
+
+// checkIfMatch answers the request itself unless If-Match names the current version of product, or
+// is absent while PRODUCTS_REQUIRE_IF_MATCH is off.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, product *entities.Product) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" && r.Context().Value("productsRequireIfMatch").(bool) {
+		problem.Write(w, r, problem.New(http.StatusPreconditionRequired, problem.CodePreconditionRequired,
+			"send the ETag of the product in If-Match"))
+		return false
+	}
+	if ifMatch != "" && !matchesETag(ifMatch, product, false) {
+		problem.Error(w, r, entities.ErrProductModified)
+		return false
+	}
+
+	return true
+}
+
+// saveProduct replaces the client-managed fields of product with input and stores it.
+func (h *ProductHandler) saveProduct(w http.ResponseWriter, r *http.Request, product *entities.Product, input dtos.CreateProductInput) {
+	product.Name = input.Name
+	product.Price = input.Price
+
+	err := product.Validate()
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.ProductDB.Update(r.Context(), product)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", productETag(product))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+func productETag(p *entities.Product) string {
+	return `"` + strconv.Itoa(p.Version) + `"`
+}
+
+// matchesETag reports whether the If-Match or If-None-Match header lists the current ETag of p or
+// is "*". If-None-Match compares weakly, ignoring a W/ prefix; If-Match only accepts strong tags.
+func matchesETag(header string, p *entities.Product, weak bool) bool {
+	etag := productETag(p)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+const acceptPatch = "application/merge-patch+json, application/json-patch+json"
+
+// patchers apply a patch document to a JSON document, by media type of the patch.
+var patchers = map[string]func(doc, patch []byte) ([]byte, error){
+	"application/merge-patch+json": jsonpatch.MergePatch,
+	"application/json-patch+json":  jsonpatch.Apply,
+}
+
+// patchProblem reports a patch that is malformed as a bad request, and one that does not fit the
+// product, such as a failed test operation, as a conflict.
+func patchProblem(err error) error {
+	if errors.Is(err, jsonpatch.ErrPathNotFound) || errors.Is(err, jsonpatch.ErrTestFailed) {
+		return problem.New(http.StatusConflict, problem.CodePatchConflict, err.Error())
+	}
+	if errors.Is(err, jsonpatch.ErrInvalidPatch) {
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidPatch, err.Error())
+	}
+
+	return err
+}
+
+var errNotOwner = problem.New(http.StatusForbidden, problem.CodeForbidden, "only the owner of the product can change it")
+
+// canChange reports whether the caller owns p or may change the products of every owner.
+func canChange(r *http.Request, p *entities.Product) bool {
+	return p.IsOwnedBy(middlewares.SubjectFromContext(r)) || middlewares.HasPermission(r, entities.PermissionManageProducts)
+}
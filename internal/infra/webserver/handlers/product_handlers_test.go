@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// productFixture serves the product routes the tests need behind bearer authentication.
+type productFixture struct {
+	router    http.Handler
+	keys      *jwks.KeySet
+	productDB *database.Product
+	userDB    *database.User
+}
+
+func newProductFixture(t *testing.T) *productFixture {
+	db, err := database.NewConnection(database.Config{Driver: database.DriverSQLite, Name: database.SQLiteMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.NewMigrator(db, migrations.All()).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &productFixture{productDB: database.NewProduct(db), userDB: database.NewUser(db)}
+	f.keys, _ = jwks.NewHMAC("HS256", []byte("secret"))
+	handler := NewProductHandler(f.productDB, f.userDB)
+
+	r := chi.NewRouter()
+	r.Use(middleware.WithValue("productsRequireIfMatch", true))
+	r.Use(middlewares.Verifier(f.keys, nil))
+	r.Use(middlewares.Authenticator)
+	r.Get("/products", handler.GetProducts)
+	r.Get("/products/trash", handler.GetTrash)
+	r.Put("/products/{id}/owner", handler.TransferProduct)
+	f.router = r
+
+	return f
+}
+
+// createProduct stores a product of owner, moving it to the trash when deleted is set.
+func (f *productFixture) createProduct(t *testing.T, name string, owner entityPkg.ID, deleted bool) *entities.Product {
+	product, _ := entities.NewProduct(name, 10.0)
+	product.OwnerID = &owner
+	assert.NoError(t, f.productDB.Create(context.Background(), product))
+	if deleted {
+		assert.NoError(t, f.productDB.Delete(context.Background(), product.ID.String(), product.Version))
+	}
+	return product
+}
+
+func (f *productFixture) do(t *testing.T, method, path, body string, claims map[string]interface{}, header http.Header) *httptest.ResponseRecorder {
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	_, token, err := f.keys.Encode(claims)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeProducts(t *testing.T, r io.Reader) []entities.Product {
+	var products []entities.Product
+	assert.NoError(t, json.NewDecoder(r).Decode(&products))
+	return products
+}
+
+func TestGetProductsPagesOnlyOnRequest(t *testing.T) {
+	f := newProductFixture(t)
+	owner := entityPkg.NewID()
+	for i := 0; i < 12; i++ {
+		f.createProduct(t, "Product "+strconv.Itoa(i), owner, false)
+	}
+	viewer := func() map[string]interface{} {
+		return map[string]interface{}{"sub": owner.String(), "role": "viewer"}
+	}
+
+	// lists stay a plain array, and without page or limit they hold every product
+	w := f.do(t, http.MethodGet, "/products", "", viewer(), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeProducts(t, w.Body), 12)
+	assert.Equal(t, "12", w.Header().Get("X-Total-Count"))
+	assert.Empty(t, w.Header().Get("Link"))
+
+	w = f.do(t, http.MethodGet, "/products?page=2", "", viewer(), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeProducts(t, w.Body), 2)
+	assert.Equal(t, "12", w.Header().Get("X-Total-Count"))
+	assert.Contains(t, w.Header().Get("Link"), `rel="prev"`)
+}
+
+func TestGetProductsOfMe(t *testing.T) {
+	f := newProductFixture(t)
+	owner := entityPkg.NewID()
+	f.createProduct(t, "Mine", owner, false)
+	f.createProduct(t, "Theirs", entityPkg.NewID(), false)
+
+	w := f.do(t, http.MethodGet, "/products?owner=me", "", map[string]interface{}{"sub": owner.String(), "role": "viewer"}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	products := decodeProducts(t, w.Body)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Mine", products[0].Name)
+
+	// a client acting for itself has no "me" to filter on
+	w = f.do(t, http.MethodGet, "/products?owner=me", "", map[string]interface{}{"sub": "client", "client_id": "client", "scope": "products:read"}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetTrashShowsOnlyOwnProducts(t *testing.T) {
+	f := newProductFixture(t)
+	owner, other := entityPkg.NewID(), entityPkg.NewID()
+	f.createProduct(t, "Product", owner, true)
+	f.createProduct(t, "Product", other, true)
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		status int
+		total  int
+	}{
+		{"owner", map[string]interface{}{"sub": owner.String(), "role": "editor"}, http.StatusOK, 1},
+		{"manager", map[string]interface{}{"sub": entityPkg.NewID().String(), "role": "admin"}, http.StatusOK, 2},
+		{"no subject", map[string]interface{}{"role": "editor"}, http.StatusForbidden, 0},
+		{"client for itself", map[string]interface{}{"sub": "client", "client_id": "client", "scope": "products:write"}, http.StatusForbidden, 0},
+	}
+	for _, c := range cases {
+		w := f.do(t, http.MethodGet, "/products/trash", "", c.claims, nil)
+		assert.Equal(t, c.status, w.Code, c.name)
+		if c.status != http.StatusOK {
+			continue
+		}
+
+		assert.Len(t, decodeProducts(t, w.Body), c.total, c.name)
+		assert.Equal(t, strconv.Itoa(c.total), w.Header().Get("X-Total-Count"), c.name)
+	}
+}
+
+func TestTransferProductChecksVersion(t *testing.T) {
+	f := newProductFixture(t)
+	product := f.createProduct(t, "Product", entityPkg.NewID(), false)
+	user, _ := entities.NewUser("caio", "caio@caio.com", "12345678")
+	assert.NoError(t, f.userDB.Create(context.Background(), user))
+
+	path := "/products/" + product.ID.String() + "/owner"
+	body := `{"owner_id":"` + user.ID.String() + `"}`
+	admin := func() map[string]interface{} {
+		return map[string]interface{}{"sub": entityPkg.NewID().String(), "role": "admin"}
+	}
+
+	w := f.do(t, http.MethodPut, path, body, admin(), nil)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = f.do(t, http.MethodPut, path, body, admin(), http.Header{"If-Match": {`"2"`}})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = f.do(t, http.MethodPut, path, body, admin(), http.Header{"If-Match": {`"1"`}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	transferred, err := f.productDB.FindByID(context.Background(), product.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, transferred.IsOwnedBy(user.ID.String()))
+
+	// the old ETag no longer matches once the owner changed
+	w = f.do(t, http.MethodPut, path, body, admin(), http.Header{"If-Match": {`"1"`}})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
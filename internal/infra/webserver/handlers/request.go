@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/caiocp/go-api/pkg/validator"
+)
+
+const maxBodyBytes = 1 << 20
+
+// normalizer is implemented by inputs that canonicalize their fields, such as email addresses,
+// before validation.
+type normalizer interface {
+	Normalize()
+}
+
+// decodeJSON reads a single JSON object of at most maxBodyBytes into dst, rejecting unknown fields,
+// normalizes it when it implements normalizer and then checks its validate tags. Every error it returns renders directly through problem.Error.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	return decode(http.MaxBytesReader(w, r.Body, maxBodyBytes), dst)
+}
+
+// decode does the work of decodeJSON for any reader.
+func decode(body io.Reader, dst interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		if tooLarge := bodyError(err); tooLarge != nil {
+			return tooLarge
+		}
+		if errors.Is(err, io.EOF) {
+			return problem.New(http.StatusBadRequest, problem.CodeInvalidJSON, "request body is empty")
+		}
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidJSON, err.Error())
+	}
+
+	if decoder.More() {
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidJSON, "request body must contain a single JSON object")
+	}
+
+	if n, ok := dst.(normalizer); ok {
+		n.Normalize()
+	}
+
+	return validator.Validate(dst)
+}
+
+// readBody returns the request body, refusing bodies over maxBodyBytes.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if tooLarge := bodyError(err); tooLarge != nil {
+		return nil, tooLarge
+	}
+
+	return body, err
+}
+
+// bodyError turns a read error caused by an oversized body into a problem, or returns nil.
+func bodyError(err error) *problem.Problem {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return problem.New(http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", maxBodyBytes))
+	}
+
+	return nil
+}
+
+// clientIP is the address of the peer that sent r, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
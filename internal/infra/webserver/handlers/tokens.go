@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+)
+
+// issueTokens signs an access token for u and stores a new refresh token in familyID. A fresh login
+// starts a new family; a rotation passes the family of the token it replaces.
+func (h *UserHandler) issueTokens(r *http.Request, u *entities.User, familyID entityPkg.ID) (dtos.GetJwtOutput, error) {
+	refreshTokenExpiresIn := r.Context().Value("refreshTokenExpiresIn").(int)
+
+	accessToken, err := signAccessToken(r, u)
+	if err != nil {
+		return dtos.GetJwtOutput{}, err
+	}
+
+	refreshToken, plain, err := entities.NewRefreshToken(u.ID, familyID, time.Second*time.Duration(refreshTokenExpiresIn))
+	if err != nil {
+		return dtos.GetJwtOutput{}, err
+	}
+
+	err = h.refreshTokenDB.Create(r.Context(), refreshToken)
+	if err != nil {
+		return dtos.GetJwtOutput{}, err
+	}
+
+	return dtos.GetJwtOutput{AccessToken: accessToken, RefreshToken: plain}, nil
+}
+
+// signAccessToken signs a first-party access token for u that expires after JWT_EXPIRESIN.
+func signAccessToken(r *http.Request, u *entities.User) (string, error) {
+	jwt := r.Context().Value("jwt").(*jwks.KeySet)
+	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"jti":  entityPkg.NewID().String(),
+		"sub":  u.ID.String(),
+		"role": string(u.Role),
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
+	}
+	if !u.Verified {
+		// GetJWT only lets unverified accounts this far when they may log in with limited access
+		claims["scope"] = entities.FormatScope(entities.UnverifiedPermissions)
+	}
+
+	_, accessToken, err := jwt.Encode(claims)
+	return accessToken, err
+}
+
+// writeTokens answers a login or a rotation with the tokens in the body or, for cookie sessions, in
+// session cookies with only the CSRF token in the body.
+func writeTokens(w http.ResponseWriter, r *http.Request, tokens dtos.GetJwtOutput, cookie bool) {
+	var body interface{} = tokens
+	if cookie {
+		jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+		refreshTokenExpiresIn := r.Context().Value("refreshTokenExpiresIn").(int)
+
+		csrf, err := sessionCookies(r).Issue(w, tokens.AccessToken, time.Second*time.Duration(jwtExpiresIn),
+			tokens.RefreshToken, time.Second*time.Duration(refreshTokenExpiresIn))
+		if err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+		body = dtos.SessionOutput{CSRFToken: csrf}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(body)
+}
+
+// sessionCookies returns the session cookie settings, or nil when cookie sessions are disabled.
+func sessionCookies(r *http.Request) *session.Cookies {
+	cookies, _ := r.Context().Value("sessionCookies").(*session.Cookies)
+	return cookies
+}
+
+// consentCookies returns the settings of the cookie that signs browsers in to the OAuth2 consent page.
+func consentCookies(r *http.Request) *session.Cookies {
+	cookies, _ := r.Context().Value("consentCookies").(*session.Cookies)
+	return cookies
+}
+
+// checkCookieMode refuses a request for a cookie session when they are disabled.
+func checkCookieMode(r *http.Request, cookie bool) error {
+	if cookie && sessionCookies(r) == nil {
+		return problem.New(http.StatusBadRequest, problem.CodeValidation, "the request contains invalid fields",
+			problem.FieldError{Field: "cookie", Code: "cookie_sessions_disabled", Message: "cookie sessions are not enabled"})
+	}
+
+	return nil
+}
+
+// refreshTokenInput reads the refresh token from the JSON body or, for a cookie session sending no
+// body, from the refresh cookie. The cookie is only honoured with a valid CSRF token.
+func refreshTokenInput(w http.ResponseWriter, r *http.Request) (dtos.RefreshTokenInput, bool, error) {
+	var input dtos.RefreshTokenInput
+
+	cookies := sessionCookies(r)
+	if cookies != nil && r.ContentLength == 0 && cookies.RefreshToken(r) != "" {
+		if !cookies.CheckCSRF(r) {
+			return input, false, problem.New(http.StatusForbidden, problem.CodeCSRFFailed,
+				"missing or invalid "+session.CSRFHeader+" header")
+		}
+		input.RefreshToken = cookies.RefreshToken(r)
+		return input, true, nil
+	}
+
+	return input, false, decodeJSON(w, r, &input)
+}
+
+// revokeSessionCookie clears the session cookies and revokes the access token they held, which a
+// bearer client would simply discard.
+func (h *UserHandler) revokeSessionCookie(w http.ResponseWriter, r *http.Request) error {
+	keys := r.Context().Value("jwt").(*jwks.KeySet)
+	cookies := sessionCookies(r)
+
+	cookies.Clear(w)
+
+	token, err := keys.Decode(cookies.AccessToken(r))
+	if err != nil || token.JwtID() == "" {
+		return nil
+	}
+
+	return h.revocations.RevokeToken(r.Context(), token.JwtID(), token.Subject(), token.Expiration())
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/caiocp/go-api/pkg/totp"
+	"gorm.io/gorm"
+)
+
+// Begin two-factor enrollment godoc
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret for the current account. It only takes effect once confirmed
+// @Description with a code from the authenticator app.
+// @Tags users
+// @Produce  json
+// @Success 200 {object} dtos.TwoFactorEnrollmentOutput
+// @Failure 401 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me/2fa [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) BeginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	twoFactorIssuer := r.Context().Value("twoFactorIssuer").(string)
+
+	u, err := h.userDB.FindByID(r.Context(), middlewares.SubjectFromContext(r))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	secret, err := u.BeginTOTPEnrollment()
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.userDB.UpdateTOTP(r.Context(), u)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.TwoFactorEnrollmentOutput{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(twoFactorIssuer, u.Email, secret),
+	})
+}
+
+// Confirm two-factor enrollment godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a first code from the authenticator app. The
+// @Description response holds single-use recovery codes that are not shown again.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.TwoFactorCodeInput true "TOTP code"
+// @Success 200 {object} dtos.RecoveryCodesOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me/2fa/confirm [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var input dtos.TwoFactorCodeInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), middlewares.SubjectFromContext(r))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = u.ConfirmTOTP(input.Code, time.Now())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	codes, plains, err := entities.NewRecoveryCodes(u.ID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.transactions.WithinTransaction(r.Context(), func(ctx context.Context, repos database.Repositories) error {
+		// the confirming code counts as used, it must not also pass a login
+		if err := repos.Users.UpdateTOTPStep(ctx, u.ID.String(), u.TOTPLastStep); err != nil {
+			return err
+		}
+		if err := repos.Users.UpdateTOTP(ctx, u); err != nil {
+			return err
+		}
+		return repos.RecoveryCodes.Replace(ctx, u.ID.String(), codes)
+	})
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.RecoveryCodesOutput{RecoveryCodes: plains})
+}
+
+// Disable two-factor godoc
+// @Summary Disable two-factor authentication
+// @Description Turn two-factor authentication off after checking the password and a TOTP or recovery
+// @Description code. Remaining recovery codes are discarded.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.DisableTwoFactorInput true "Password and code"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/me/2fa/disable [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var input dtos.DisableTwoFactorInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), middlewares.SubjectFromContext(r))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if !u.TOTPEnabled {
+		problem.Error(w, r, entities.ErrTwoFactorNotEnrolled)
+		return
+	}
+	if !u.ValidatePassword(input.CurrentPassword) {
+		problem.Error(w, r, entities.ErrIncorrectPassword)
+		return
+	}
+
+	err = h.checkSecondFactor(r, u, input.Code)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u.DisableTOTP()
+	err = h.transactions.WithinTransaction(r.Context(), func(ctx context.Context, repos database.Repositories) error {
+		if err := repos.Users.UpdateTOTP(ctx, u); err != nil {
+			return err
+		}
+		return repos.RecoveryCodes.Replace(ctx, u.ID.String(), nil)
+	})
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Complete two-factor login godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token from /users/generate_token and a TOTP or recovery code for
+// @Description an access token and a refresh token. A challenge allows a few wrong codes before it is
+// @Description discarded, and wrong codes count toward the account lockout like wrong passwords.
+// @Description "cookie": true starts a cookie session as in /users/generate_token.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.TwoFactorLoginInput true "Challenge token and code"
+// @Success 200 {object} dtos.GetJwtOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/generate_token/2fa [post]
+func (h *UserHandler) CompleteTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var input dtos.TwoFactorLoginInput
+	err := decodeJSON(w, r, &input)
+	if err == nil {
+		err = checkCookieMode(r, input.Cookie)
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	challenge, err := h.challengeDB.FindByHash(r.Context(), entities.HashTwoFactorChallenge(input.ChallengeToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrTwoFactorChallenge)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	err = h.challengeDB.ReserveAttempt(r.Context(), challenge.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), challenge.UserID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrTwoFactorChallenge)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.checkSecondFactor(r, u, input.Code)
+	if errors.Is(err, entities.ErrInvalidTwoFactorCode) {
+		h.loginGuard.Fail(r.Context(), u.Email, clientIP(r))
+		problem.Error(w, r, entities.ErrTwoFactorChallenge)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.challengeDB.MarkUsed(r.Context(), challenge.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	h.loginGuard.Succeed(r.Context(), u.Email)
+
+	tokens, err := h.issueTokens(r, u, entityPkg.NewID())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeTokens(w, r, tokens, input.Cookie)
+}
+
+// startTwoFactorChallenge answers a correct password for an account with two-factor authentication
+// by handing out a challenge instead of tokens.
+func (h *UserHandler) startTwoFactorChallenge(w http.ResponseWriter, r *http.Request, u *entities.User) {
+	challengeExpiresIn := r.Context().Value("twoFactorChallengeExpiresIn").(int)
+
+	challenge, plain, err := entities.NewTwoFactorChallenge(u.ID, time.Second*time.Duration(challengeExpiresIn))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.challengeDB.Create(r.Context(), challenge)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dtos.TwoFactorChallengeOutput{ChallengeToken: plain, ExpiresIn: challengeExpiresIn})
+}
+
+// checkSecondFactor accepts a TOTP code or an unused recovery code of u, consuming either. It returns
+// entities.ErrInvalidTwoFactorCode when neither matches.
+func (h *UserHandler) checkSecondFactor(r *http.Request, u *entities.User, code string) error {
+	if u.CheckTOTP(code, time.Now()) {
+		return h.userDB.UpdateTOTPStep(r.Context(), u.ID.String(), u.TOTPLastStep)
+	}
+
+	return h.recoveryCodeDB.Use(r.Context(), u.ID.String(), entities.HashRecoveryCode(code))
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/audit"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/database/migrations"
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/lockout"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/pkg/totp"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// twoFactorFixture serves the login and two-factor routes for a verified user caio@caio.com with
+// password 12345678 who has started enrolling secret, locking the account after three failures.
+type twoFactorFixture struct {
+	router         http.Handler
+	user           *entities.User
+	secret         string
+	token          string
+	userDB         *database.User
+	recoveryCodeDB *database.RecoveryCode
+}
+
+func newTwoFactorFixture(t *testing.T, enabled bool) *twoFactorFixture {
+	db, err := database.NewConnection(database.Config{Driver: database.DriverSQLite, Name: database.SQLiteMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.NewMigrator(db, migrations.All()).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &twoFactorFixture{userDB: database.NewUser(db), recoveryCodeDB: database.NewRecoveryCode(db)}
+	f.user, _ = entities.NewUser("caio", "caio@caio.com", "12345678")
+	f.user.Verified = true
+	f.secret, _ = f.user.BeginTOTPEnrollment()
+	f.user.TOTPEnabled = enabled
+	assert.NoError(t, f.userDB.Create(context.Background(), f.user))
+
+	keys, _ := jwks.NewHMAC("HS256", []byte("secret"))
+	guard := lockout.NewGuard(lockout.Policy{MaxFailures: 3, Lockout: time.Minute, Window: time.Minute},
+		lockout.Policy{MaxFailures: 50, Lockout: time.Minute, Window: time.Minute}, audit.NewWriter(io.Discard))
+	userHandler := NewUserHandler(f.userDB, database.NewRefreshToken(db), database.NewAPIKey(db), database.NewEmailVerificationToken(db),
+		f.recoveryCodeDB, database.NewTwoFactorChallenge(db), database.NewTransactionManager(db), revocation.NewMemory(), nil, guard)
+
+	r := chi.NewRouter()
+	r.Use(middleware.WithValue("jwt", keys))
+	r.Use(middleware.WithValue("jwtExpiresIn", 300))
+	r.Use(middleware.WithValue("refreshTokenExpiresIn", 3600))
+	r.Use(middleware.WithValue("unverifiedLogin", "refuse"))
+	r.Use(middleware.WithValue("twoFactorChallengeExpiresIn", 300))
+	r.Post("/users/generate_token", userHandler.GetJWT)
+	r.Post("/users/generate_token/2fa", userHandler.CompleteTwoFactor)
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares.Verifier(keys, nil))
+		r.Use(middlewares.Authenticator)
+		r.Post("/users/me/2fa/confirm", userHandler.ConfirmTwoFactor)
+		r.Post("/users/me/2fa/disable", userHandler.DisableTwoFactor)
+	})
+	f.router = r
+
+	_, f.token, _ = keys.Encode(map[string]interface{}{"sub": f.user.ID.String(), "role": "viewer", "exp": time.Now().Add(time.Minute).Unix()})
+	return f
+}
+
+func (f *twoFactorFixture) post(path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+f.token)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+// login signs in with password and returns the challenge token, or an empty string when the login
+// did not ask for a second factor.
+func (f *twoFactorFixture) login(t *testing.T, password string) (*httptest.ResponseRecorder, string) {
+	w := f.post("/users/generate_token", `{"email":"caio@caio.com","password":"`+password+`"}`)
+	if w.Code != http.StatusAccepted {
+		return w, ""
+	}
+
+	var challenge dtos.TwoFactorChallengeOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&challenge))
+	return w, challenge.ChallengeToken
+}
+
+func TestConfirmAndDisableTwoFactor(t *testing.T) {
+	f := newTwoFactorFixture(t, false)
+
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(f.secret, step)
+	w := f.post("/users/me/2fa/confirm", `{"code":"`+code+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var output dtos.RecoveryCodesOutput
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&output))
+	assert.NotEmpty(t, output.RecoveryCodes)
+
+	found, _ := f.userDB.FindByID(context.Background(), f.user.ID.String())
+	assert.True(t, found.TOTPEnabled)
+	assert.Equal(t, step, found.TOTPLastStep)
+
+	// the recovery codes were stored together with the user, so one of them turns two-factor off
+	w = f.post("/users/me/2fa/disable", `{"current_password":"12345678","code":"`+output.RecoveryCodes[0]+`"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	found, _ = f.userDB.FindByID(context.Background(), f.user.ID.String())
+	assert.False(t, found.TOTPEnabled)
+	err := f.recoveryCodeDB.Use(context.Background(), f.user.ID.String(), entities.HashRecoveryCode(output.RecoveryCodes[1]))
+	assert.ErrorIs(t, err, entities.ErrInvalidTwoFactorCode)
+}
+
+func TestPasswordAloneDoesNotClearLockout(t *testing.T) {
+	f := newTwoFactorFixture(t, true)
+
+	// a correct password only opens a challenge, so the failures before it still count
+	w, _ := f.login(t, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = f.login(t, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	_, challenge := f.login(t, "12345678")
+	assert.NotEmpty(t, challenge)
+	w, _ = f.login(t, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w, _ = f.login(t, "12345678")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestCompleteTwoFactorClearsLockout(t *testing.T) {
+	f := newTwoFactorFixture(t, true)
+
+	w, _ := f.login(t, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = f.login(t, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	_, challenge := f.login(t, "12345678")
+	code, _ := totp.Code(f.secret, totp.Step(time.Now()))
+	w = f.post("/users/generate_token/2fa", `{"challenge_token":"`+challenge+`","code":"`+code+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, _ = f.login(t, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	_, challenge = f.login(t, "12345678")
+	assert.NotEmpty(t, challenge)
+}
+
+func TestWrongSecondFactorCountsTowardLockout(t *testing.T) {
+	f := newTwoFactorFixture(t, true)
+
+	_, challenge := f.login(t, "12345678")
+	for i := 0; i < 3; i++ {
+		w := f.post("/users/generate_token/2fa", `{"challenge_token":"`+challenge+`","code":"000000"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w, _ := f.login(t, "12345678")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
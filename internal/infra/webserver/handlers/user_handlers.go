@@ -1,115 +1,447 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/caiocp/go-api/internal/dtos"
 	"github.com/caiocp/go-api/internal/entities"
 	"github.com/caiocp/go-api/internal/infra/database"
-	"github.com/go-chi/jwtauth"
+	"github.com/caiocp/go-api/internal/infra/lockout"
+	"github.com/caiocp/go-api/internal/infra/mail"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/middlewares"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	entityPkg "github.com/caiocp/go-api/pkg/entities"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
-type Error struct {
-	Message string `json:"message"`
-}
-
 type UserHandler struct {
-	userDB database.UserInterface
+	userDB              database.UserInterface
+	refreshTokenDB      database.RefreshTokenInterface
+	apiKeyDB            database.APIKeyInterface
+	verificationTokenDB database.EmailVerificationTokenInterface
+	recoveryCodeDB      database.RecoveryCodeInterface
+	challengeDB         database.TwoFactorChallengeInterface
+	transactions        database.TransactionManagerInterface
+	revocations         revocation.Store
+	mailer              mail.Mailer
+	loginGuard          *lockout.Guard
 }
 
-func NewUserHandler(userDB database.UserInterface) *UserHandler {
+func NewUserHandler(userDB database.UserInterface, refreshTokenDB database.RefreshTokenInterface,
+	apiKeyDB database.APIKeyInterface, verificationTokenDB database.EmailVerificationTokenInterface,
+	recoveryCodeDB database.RecoveryCodeInterface, challengeDB database.TwoFactorChallengeInterface,
+	transactions database.TransactionManagerInterface, revocations revocation.Store, mailer mail.Mailer,
+	loginGuard *lockout.Guard) *UserHandler {
 	return &UserHandler{
-		userDB: userDB,
+		userDB:              userDB,
+		refreshTokenDB:      refreshTokenDB,
+		apiKeyDB:            apiKeyDB,
+		verificationTokenDB: verificationTokenDB,
+		recoveryCodeDB:      recoveryCodeDB,
+		challengeDB:         challengeDB,
+		transactions:        transactions,
+		revocations:         revocations,
+		mailer:              mailer,
+		loginGuard:          loginGuard,
 	}
 }
 
+var (
+	dummyOnce sync.Once
+	dummy     *entities.User
+)
+
+// dummyUser stands in for unknown accounts at login. It is built on first use, so that its hash
+// comes from the hasher configured at startup and costs as much to check as a real one.
+func dummyUser() *entities.User {
+	dummyOnce.Do(func() {
+		hash, _ := entities.HashPassword("not-a-real-password")
+		dummy = &entities.User{Password: hash}
+	})
+
+	return dummy
+}
+
+// checkPassword finds the account with email and checks its password, recording a failure with the
+// login guard; success is left for the caller to record once every factor has passed. It returns how
+// long to wait while the guard holds the login back, and entities.ErrInvalidCredentials for an unknown
+// email or a wrong password. A correct password also upgrades a hash made by an older hasher.
+func (h *UserHandler) checkPassword(ctx context.Context, email, password, ip string) (*entities.User, time.Duration, error) {
+	if wait := h.loginGuard.Wait(ctx, email, ip); wait > 0 {
+		return nil, wait, nil
+	}
+
+	u, err := h.userDB.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, err
+	}
+
+	// unknown emails pay for a hash comparison too, so neither the response nor its timing tells
+	// them apart from a wrong password
+	if u == nil {
+		dummyUser().ValidatePassword(password)
+	}
+	if u == nil || !u.ValidatePassword(password) {
+		h.loginGuard.Fail(ctx, email, ip)
+		return nil, 0, entities.ErrInvalidCredentials
+	}
+
+	// the plain password is only at hand now, so hashes from an older hasher are upgraded here. A
+	// failed upgrade does not fail the login; it is tried again next time.
+	oldHash := u.Password
+	if changed, err := u.RehashPassword(password); err == nil && changed {
+		h.userDB.ReplacePasswordHash(ctx, u.ID.String(), oldHash, u.Password)
+	}
+
+	return u, 0, nil
+}
+
 // Get JWT godoc
 // @Summary Get JWT
-// @Description Get JWT
+// @Description Exchange credentials for an access token and a refresh token. Accounts whose email
+// @Description address is not verified are refused, or only get the products:read scope when
+// @Description UNVERIFIED_LOGIN is limited. Accounts with two-factor authentication get a 202 with a
+// @Description challenge token instead, to complete at /users/generate_token/2fa. Repeated failures
+// @Description slow down and then lock out the account and the client IP with a 429. With "cookie": true
+// @Description and SESSION_COOKIES enabled, the tokens are set as HttpOnly cookies and the body only
+// @Description holds the CSRF token to send in the X-CSRF-Token header on unsafe requests.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param request body dtos.GetJWTInput true "User credentials"
 // @Success 200 {object} dtos.GetJwtOutput
-// @Failure 400
-// @Failure 401
-// @failure 404 {object} Error
-// @Failure 500 {object} Error
+// @Success 200 {object} dtos.SessionOutput
+// @Success 202 {object} dtos.TwoFactorChallengeOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /users/generate_token [post]
 func (h *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
+	unverifiedLogin := r.Context().Value("unverifiedLogin").(string)
+
 	var user dtos.GetJWTInput
-	jwt := r.Context().Value("jwt").(*jwtauth.JWTAuth)
-	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
 
-	err := json.NewDecoder(r.Body).Decode(&user)
+	err := decodeJSON(w, r, &user)
+	if err == nil {
+		err = checkCookieMode(r, user.Cookie)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
-	u, err := h.userDB.FindByEmail(user.Email)
+	u, wait, err := h.checkPassword(r.Context(), user.Email, user.Password, clientIP(r))
+	if wait > 0 {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeTooManyAttempts,
+			fmt.Sprintf("too many failed logins, try again in %d seconds", retryAfter)))
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
-		err := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(err)
+		problem.Error(w, r, err)
 		return
 	}
 
-	if !u.ValidatePassword(user.Password) {
-		http.Error(w, "invalid password", http.StatusUnauthorized)
+	if !u.Verified && unverifiedLogin != "limited" {
+		problem.Error(w, r, entities.ErrEmailNotVerified)
 		return
 	}
 
-	_, token, _ := jwt.Encode(map[string]interface{}{
-		"sub": u.ID.String(),
-		"exp": time.Now().Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
-	})
+	if u.TOTPEnabled {
+		// the login only counts as a success once the second factor passed too
+		h.startTwoFactorChallenge(w, r, u)
+		return
+	}
+	h.loginGuard.Succeed(r.Context(), u.Email)
 
-	accessToken := dtos.GetJwtOutput{AccessToken: token}
+	tokens, err := h.issueTokens(r, u, entityPkg.NewID())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(accessToken)
+	writeTokens(w, r, tokens, user.Cookie)
+}
+
+// Refresh token godoc
+// @Summary Refresh JWT
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token
+// @Description works once; presenting a used one revokes every token issued from the same login.
+// @Description Cookie sessions send no body and the X-CSRF-Token header instead, and get new cookies.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.RefreshTokenInput true "Refresh token"
+// @Success 200 {object} dtos.GetJwtOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/refresh_token [post]
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	input, fromCookie, err := refreshTokenInput(w, r)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	token, err := h.refreshTokenDB.FindByHash(r.Context(), entities.HashRefreshToken(input.RefreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if token.ClientID != "" {
+		// tokens issued to OAuth2 clients rotate through /oauth/token with client authentication
+		problem.Error(w, r, entities.ErrInvalidRefreshToken)
+		return
+	}
+
+	if token.IsExpired() {
+		problem.Error(w, r, entities.ErrRefreshTokenExpired)
+		return
+	}
+
+	err = h.refreshTokenDB.Revoke(r.Context(), token.ID.String())
+	if errors.Is(err, entities.ErrRefreshTokenReused) {
+		// a used token came back: assume it leaked and cut off everyone holding this login
+		if err := h.refreshTokenDB.RevokeFamily(r.Context(), token.FamilyID.String()); err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+		problem.Error(w, r, entities.ErrRefreshTokenReused)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), token.UserID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, u, token.FamilyID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeTokens(w, r, tokens, fromCookie)
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Revoke the refresh token and every token rotated from the same login. Cookie sessions
+// @Description send no body and the X-CSRF-Token header instead, and have their cookies cleared.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.RefreshTokenInput true "Refresh token"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/logout [post]
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	input, fromCookie, err := refreshTokenInput(w, r)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if fromCookie {
+		err = h.revokeSessionCookie(w, r)
+		if err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+	}
+
+	token, err := h.refreshTokenDB.FindByHash(r.Context(), entities.HashRefreshToken(input.RefreshToken))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if token != nil {
+		err = h.refreshTokenDB.RevokeFamily(r.Context(), token.FamilyID.String())
+		if err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // Create user godoc
 // @Summary Create user
-// @Description Create user
+// @Description Create an unverified user and email them a verification link. The password has to be
+// @Description PASSWORD_MIN_LENGTH long and not on the breached password list.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param request body dtos.CreateUserInput true "User request"
 // @Success 201
-// @Failure 500 {object} Error
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user dtos.CreateUserInput
 
-	err := json.NewDecoder(r.Body).Decode(&user)
+	err := decodeJSON(w, r, &user)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		error := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(error)
+		problem.Error(w, r, err)
 		return
 	}
 
 	u, err := entities.NewUser(user.Name, user.Email, user.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		error := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(error)
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.userDB.Create(r.Context(), u)
+	if err != nil {
+		problem.Error(w, r, err)
 		return
 	}
 
-	err = h.userDB.Create(u)
+	err = h.sendVerification(r, u)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		error := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(error)
+		problem.Error(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
+
+// Update user role godoc
+// @Summary Assign a role to a user
+// @Description Assign the admin, editor or viewer role to a user. Requires the users:manage permission.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID" Format(uuid)
+// @Param request body dtos.UpdateUserRoleInput true "Role request"
+// @Success 200
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/{id}/role [put]
+// @Security ApiKeyAuth
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var input dtos.UpdateUserRoleInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	role, err := entities.ParseRole(input.Role)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.userDB.UpdateRole(r.Context(), id, role)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Unlock user godoc
+// @Summary Lift a login lockout
+// @Description Clear the failed logins of a user so they can log in again before the lockout expires.
+// @Description Requires the users:manage permission.
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID" Format(uuid)
+// @Success 204
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/{id}/unlock [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	u, err := h.userDB.FindByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	h.loginGuard.Unlock(r.Context(), u.Email, "admin "+middlewares.SubjectFromContext(r))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Revoke user tokens godoc
+// @Summary Revoke every token of a user
+// @Description Immediately invalidate every access token issued to the user so far and revoke their
+// @Description refresh tokens and API keys. Requires the users:manage permission.
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID" Format(uuid)
+// @Success 204
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/{id}/revoke_tokens [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) RevokeTokens(w http.ResponseWriter, r *http.Request) {
+	jwtExpiresIn := r.Context().Value("jwtExpiresIn").(int)
+	id := chi.URLParam(r, "id")
+
+	u, err := h.userDB.FindByID(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	err = h.revocations.RevokeSubject(r.Context(), u.ID.String(), now, now.Add(time.Second*time.Duration(jwtExpiresIn)))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.refreshTokenDB.RevokeUser(r.Context(), u.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.apiKeyDB.RevokeUser(r.Context(), u.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/caiocp/go-api/internal/dtos"
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/mail"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"gorm.io/gorm"
+)
+
+// Verify email godoc
+// @Summary Verify an email address
+// @Description Confirm the address of an account with the token from the verification email
+// @Tags users
+// @Produce  json
+// @Param token query string true "Verification token"
+// @Success 200 {object} entities.User
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/verify [get]
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	plain := r.URL.Query().Get("token")
+	if plain == "" {
+		problem.Error(w, r, entities.ErrInvalidVerificationToken)
+		return
+	}
+
+	token, err := h.verificationTokenDB.FindByHash(r.Context(), entities.HashEmailVerificationToken(plain))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidVerificationToken)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if token.IsExpired() || token.IsUsed() {
+		problem.Error(w, r, entities.ErrInvalidVerificationToken)
+		return
+	}
+
+	u, err := h.userDB.FindByID(r.Context(), token.UserID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidVerificationToken)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// a link sent before the address changed must not vouch for the new one
+	if u.Email != token.Email {
+		problem.Error(w, r, entities.ErrInvalidVerificationToken)
+		return
+	}
+
+	err = h.verificationTokenDB.MarkUsed(r.Context(), token.ID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	err = h.userDB.MarkVerified(r.Context(), u.ID.String(), token.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, entities.ErrInvalidVerificationToken)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	u.Verified = true
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+// Resend verification godoc
+// @Summary Resend the verification email
+// @Description Send a new verification link to an unverified account. At most one link is sent per
+// @Description VERIFICATION_RESEND_INTERVAL, and the response is the same whether or not an
+// @Description unverified account uses the address.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body dtos.ResendVerificationInput true "Account email"
+// @Success 202
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /users/verify/resend [post]
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	resendInterval := r.Context().Value("verificationResendInterval").(int)
+
+	var input dtos.ResendVerificationInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.userDB.FindByEmail(r.Context(), input.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if u.Verified {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	latest, err := h.verificationTokenDB.FindLatest(r.Context(), u.ID.String())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, err)
+		return
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < time.Second*time.Duration(resendInterval) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	err = h.sendVerification(r, u)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendVerification stores a new verification token for the current address of u and mails the link.
+func (h *UserHandler) sendVerification(r *http.Request, u *entities.User) error {
+	appBaseURL := r.Context().Value("appBaseURL").(string)
+	verificationExpiresIn := r.Context().Value("verificationExpiresIn").(int)
+
+	ttl := time.Second * time.Duration(verificationExpiresIn)
+	token, plain, err := entities.NewEmailVerificationToken(u, ttl)
+	if err != nil {
+		return err
+	}
+
+	err = h.verificationTokenDB.Create(r.Context(), token)
+	if err != nil {
+		return err
+	}
+
+	link := appBaseURL + "/users/verify?token=" + url.QueryEscape(plain)
+	sendMail(h.mailer, mail.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Open this link within %s to confirm your email address:\n\n%s\n\n"+
+			"If you did not create an account, ignore this message.", ttl, link),
+	})
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+	"gorm.io/gorm"
+)
+
+// APIKeyHeader carries a personal API key in place of a bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// lastUsedResolution bounds how often a busy key writes its last-used timestamp.
+const lastUsedResolution = time.Minute
+
+// APIKey authenticates requests that send an X-API-Key header. It goes right after Verifier and
+// stores a token holding the owner's current role and the key's scope with jwtauth.NewContext, so
+// Authenticator and RequirePermission treat both credentials alike. Requests without the header
+// pass through untouched.
+func APIKey(keys database.APIKeyInterface, users database.UserInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain := r.Header.Get(APIKeyHeader)
+			if plain == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.Header.Get("Authorization") != "" {
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized,
+					"send either a bearer token or an API key, not both"))
+				return
+			}
+
+			key, user, err := resolveAPIKey(r, keys, users, plain)
+			if err != nil {
+				problem.Error(w, r, err)
+				return
+			}
+
+			now := time.Now()
+			if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+				// A failed bookkeeping write must not fail an otherwise valid request.
+				_ = keys.TouchLastUsed(r.Context(), key.ID.String(), now)
+			}
+
+			token, err := apiKeyToken(key, user)
+			if err != nil {
+				problem.Error(w, r, err)
+				return
+			}
+
+			// the key replaces any session cookie sent along, so CSRF does not apply
+			ctx := context.WithValue(r.Context(), cookieAuthKey{}, false)
+			next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(ctx, token, nil)))
+		})
+	}
+}
+
+func resolveAPIKey(r *http.Request, keys database.APIKeyInterface, users database.UserInterface, plain string) (*entities.APIKey, *entities.User, error) {
+	invalid := problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, entities.ErrInvalidAPIKey.Error())
+
+	key, err := keys.FindByHash(r.Context(), entities.HashAPIKey(plain))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, invalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !key.IsActive() {
+		return nil, nil, invalid
+	}
+
+	user, err := users.FindByID(r.Context(), key.UserID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, invalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.Verified {
+		return nil, nil, entities.ErrEmailNotVerified
+	}
+
+	return key, user, nil
+}
+
+// apiKeyToken builds the unsigned token that stands in for a verified JWT. The jti names the key so
+// it can be told apart from issued tokens; iat is when the key was created, so revoking every token
+// of the user issued so far covers it, and exp follows the key's own expiry when it has one.
+func apiKeyToken(key *entities.APIKey, user *entities.User) (jwt.Token, error) {
+	claims := map[string]interface{}{
+		jwt.JwtIDKey:    "apikey:" + key.ID.String(),
+		jwt.SubjectKey:  user.ID.String(),
+		jwt.IssuedAtKey: key.CreatedAt,
+		"role":          string(user.Role),
+	}
+	if key.Scope != "" {
+		claims["scope"] = key.Scope
+	}
+	if key.ExpiresAt != nil {
+		claims[jwt.ExpirationKey] = *key.ExpiresAt
+	}
+
+	token := jwt.New()
+	for name, value := range claims {
+		if err := token.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return token, nil
+}
@@ -0,0 +1,162 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/database"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fakeAPIKeys struct {
+	database.APIKeyInterface
+	keys map[string]*entities.APIKey
+}
+
+func (f *fakeAPIKeys) FindByHash(ctx context.Context, hash string) (*entities.APIKey, error) {
+	for _, key := range f.keys {
+		if key.KeyHash == hash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAPIKeys) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	f.keys[id].LastUsedAt = &usedAt
+	return nil
+}
+
+type fakeUsers struct {
+	database.UserInterface
+	users map[string]*entities.User
+}
+
+func (f *fakeUsers) FindByID(ctx context.Context, id string) (*entities.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestAPIKey(t *testing.T) {
+	user, _ := entities.NewUser("John Doe", "john@example.com", "12345678")
+	user.Role = entities.RoleEditor
+	user.Verified = true
+
+	scoped, scopedPlain, _ := entities.NewAPIKey(user.ID, "read only", []string{"products:read"}, nil)
+	full, fullPlain, _ := entities.NewAPIKey(user.ID, "ci", nil, nil)
+	revoked, revokedPlain, _ := entities.NewAPIKey(user.ID, "old", nil, nil)
+	now := time.Now()
+	revoked.RevokedAt = &now
+
+	keys := &fakeAPIKeys{keys: map[string]*entities.APIKey{
+		scoped.ID.String():  scoped,
+		full.ID.String():    full,
+		revoked.ID.String(): revoked,
+	}}
+	users := &fakeUsers{users: map[string]*entities.User{user.ID.String(): user}}
+
+	var subject string
+	handler := func(permission entities.Permission) http.Handler {
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject = SubjectFromContext(r)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return APIKey(keys, users)(Authenticator(RequirePermission(permission)(ok)))
+	}
+
+	request := func(key string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		if key != "" {
+			r.Header.Set(APIKeyHeader, key)
+		}
+		return r
+	}
+
+	cases := []struct {
+		name       string
+		key        string
+		permission entities.Permission
+		status     int
+	}{
+		{"full key uses the role", fullPlain, entities.PermissionWriteProducts, http.StatusNoContent},
+		{"role still bounds the key", fullPlain, entities.PermissionManageUsers, http.StatusForbidden},
+		{"scope allows", scopedPlain, entities.PermissionReadProducts, http.StatusNoContent},
+		{"scope denies", scopedPlain, entities.PermissionWriteProducts, http.StatusForbidden},
+		{"revoked", revokedPlain, entities.PermissionReadProducts, http.StatusUnauthorized},
+		{"unknown", "gak_00000000_nope", entities.PermissionReadProducts, http.StatusUnauthorized},
+		{"missing", "", entities.PermissionReadProducts, http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(c.permission).ServeHTTP(w, request(c.key))
+			assert.Equal(t, c.status, w.Code)
+		})
+	}
+
+	assert.Equal(t, user.ID.String(), subject)
+	assert.NotNil(t, keys.keys[full.ID.String()].LastUsedAt)
+	assert.Nil(t, keys.keys[revoked.ID.String()].LastUsedAt)
+
+	r := request(fullPlain)
+	r.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	handler(entities.PermissionReadProducts).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	delete(users.users, user.ID.String())
+	w = httptest.NewRecorder()
+	handler(entities.PermissionReadProducts).ServeHTTP(w, request(fullPlain))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	user, _ := entities.NewUser("John Doe", "john@example.com", "12345678")
+	user.Verified = true
+	expiresAt := time.Now().Add(time.Hour)
+	key, plain, _ := entities.NewAPIKey(user.ID, "ci", nil, &expiresAt)
+	past := time.Now().Add(-time.Second)
+	key.ExpiresAt = &past
+
+	keys := &fakeAPIKeys{keys: map[string]*entities.APIKey{key.ID.String(): key}}
+	users := &fakeUsers{users: map[string]*entities.User{user.ID.String(): user}}
+
+	r := httptest.NewRequest(http.MethodGet, "/products", nil)
+	r.Header.Set(APIKeyHeader, plain)
+	w := httptest.NewRecorder()
+	APIKey(keys, users)(Authenticator(http.NotFoundHandler())).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPIKeyHonoursSubjectRevocation(t *testing.T) {
+	user, _ := entities.NewUser("John Doe", "john@example.com", "12345678")
+	user.Verified = true
+	older, olderPlain, _ := entities.NewAPIKey(user.ID, "old", nil, nil)
+	older.CreatedAt = time.Now().Add(-time.Hour)
+	newer, newerPlain, _ := entities.NewAPIKey(user.ID, "new", nil, nil)
+
+	keys := &fakeAPIKeys{keys: map[string]*entities.APIKey{older.ID.String(): older, newer.ID.String(): newer}}
+	users := &fakeUsers{users: map[string]*entities.User{user.ID.String(): user}}
+	store := revocation.NewMemory()
+	assert.NoError(t, store.RevokeSubject(context.Background(), user.ID.String(), time.Now().Add(-time.Minute), time.Now().Add(time.Hour)))
+
+	handler := APIKey(keys, users)(Authenticator(RejectRevoked(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))))
+	for plain, status := range map[string]int{olderPlain: http.StatusUnauthorized, newerPlain: http.StatusNoContent} {
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set(APIKeyHeader, plain)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, status, w.Code)
+	}
+}
@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Authenticator rejects requests whose token was not verified by jwtauth.Verifier. It behaves like
+// jwtauth.Authenticator but answers with a problem+json body.
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, err.Error()))
+			return
+		}
+
+		if token == nil || jwt.Validate(token) != nil {
+			problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "invalid or expired token"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
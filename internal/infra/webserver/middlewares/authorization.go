@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/go-chi/jwtauth"
+)
+
+// RequirePermission lets the request through only when the role claim of the verified token grants
+// permission and, if the token carries a scope claim, the scope lists it too. Tokens an OAuth2
+// client obtained for itself have no role and are limited by their scope alone. It must run after
+// Authenticator.
+func RequirePermission(permission entities.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasPermission(r, permission) {
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+					"missing permission "+string(permission)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasPermission reports whether the verified token grants permission, by the rules of
+// RequirePermission. Handlers use it for checks that depend on the resource, such as ownership.
+func HasPermission(r *http.Request, permission entities.Permission) bool {
+	role := RoleFromContext(r)
+	scope, scoped := ScopeFromContext(r)
+	granted := role.Can(permission)
+	if role == "" && ClientFromContext(r) != "" {
+		granted = scoped
+	}
+
+	return granted && (!scoped || entities.ScopeAllows(scope, permission))
+}
+
+// RoleFromContext returns the role claim of the verified token, or an empty role when there is none.
+func RoleFromContext(r *http.Request) entities.Role {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return ""
+	}
+
+	role, _ := claims["role"].(string)
+	return entities.Role(role)
+}
+
+// SubjectFromContext returns the sub claim of the verified token, or an empty string when there is none.
+func SubjectFromContext(r *http.Request) string {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return ""
+	}
+
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+// UserFromContext returns the ID of the user the verified token acts for. It is empty for tokens an
+// OAuth2 client obtained for itself, whose sub claim is the client ID.
+func UserFromContext(r *http.Request) string {
+	if RoleFromContext(r) == "" && ClientFromContext(r) != "" {
+		return ""
+	}
+
+	return SubjectFromContext(r)
+}
+
+// ScopeFromContext returns the scope claim of the verified token. The second result is false when
+// the token has no scope claim and is limited by its role alone.
+func ScopeFromContext(r *http.Request) (string, bool) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return "", false
+	}
+
+	scope, ok := claims["scope"].(string)
+	return scope, ok
+}
+
+// ClientFromContext returns the client_id claim of a token issued through OAuth2, or an empty string
+// for first-party tokens.
+func ClientFromContext(r *http.Request) string {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return ""
+	}
+
+	clientID, _ := claims["client_id"].(string)
+	return clientID
+}
+
+// FirstPartyOnly refuses tokens issued to OAuth2 clients, keeping account management out of reach
+// of delegated access. It must run after Authenticator.
+func FirstPartyOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ClientFromContext(r) != "" {
+			problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+				"tokens issued to OAuth2 clients cannot be used here"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
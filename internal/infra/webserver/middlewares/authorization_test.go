@@ -0,0 +1,169 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+)
+
+var tokenAuth = jwtauth.New("HS256", []byte("secret"), nil)
+
+func protected(permission entities.Permission) http.Handler {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return jwtauth.Verifier(tokenAuth)(Authenticator(RequirePermission(permission)(ok)))
+}
+
+func requestWithRole(t *testing.T, role string) *http.Request {
+	_, token, err := tokenAuth.Encode(map[string]interface{}{
+		"sub":  "user",
+		"role": role,
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/products", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestRequirePermission(t *testing.T) {
+	cases := []struct {
+		role       string
+		permission entities.Permission
+		status     int
+	}{
+		{"viewer", entities.PermissionReadProducts, http.StatusNoContent},
+		{"viewer", entities.PermissionWriteProducts, http.StatusForbidden},
+		{"editor", entities.PermissionWriteProducts, http.StatusNoContent},
+		{"editor", entities.PermissionManageUsers, http.StatusForbidden},
+		{"admin", entities.PermissionManageUsers, http.StatusNoContent},
+		{"editor", entities.PermissionManageProducts, http.StatusForbidden},
+		{"admin", entities.PermissionManageProducts, http.StatusNoContent},
+		{"", entities.PermissionReadProducts, http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		protected(c.permission).ServeHTTP(w, requestWithRole(t, c.role))
+		assert.Equal(t, c.status, w.Code, "role %q permission %s", c.role, c.permission)
+	}
+}
+
+func TestAuthenticatorWithoutToken(t *testing.T) {
+	w := httptest.NewRecorder()
+	protected(entities.PermissionReadProducts).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequirePermissionHonoursScope(t *testing.T) {
+	_, token, err := tokenAuth.Encode(map[string]interface{}{
+		"sub":   "user",
+		"role":  "editor",
+		"scope": "products:read",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	})
+	assert.NoError(t, err)
+
+	for permission, status := range map[entities.Permission]int{
+		entities.PermissionReadProducts:  http.StatusNoContent,
+		entities.PermissionWriteProducts: http.StatusForbidden,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		protected(permission).ServeHTTP(w, r)
+		assert.Equal(t, status, w.Code, "permission %s", permission)
+	}
+}
+
+func TestRequirePermissionForClientTokens(t *testing.T) {
+	encode := func(claims map[string]interface{}) string {
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+		_, token, err := tokenAuth.Encode(claims)
+		assert.NoError(t, err)
+		return token
+	}
+
+	cases := []struct {
+		name       string
+		token      string
+		permission entities.Permission
+		status     int
+	}{
+		{"client scope allows", encode(map[string]interface{}{"sub": "client", "client_id": "client", "scope": "products:read"}),
+			entities.PermissionReadProducts, http.StatusNoContent},
+		{"client scope denies", encode(map[string]interface{}{"sub": "client", "client_id": "client", "scope": "products:read"}),
+			entities.PermissionWriteProducts, http.StatusForbidden},
+		{"client without scope", encode(map[string]interface{}{"sub": "client", "client_id": "client"}),
+			entities.PermissionReadProducts, http.StatusForbidden},
+		{"delegated user keeps role bound", encode(map[string]interface{}{"sub": "user", "client_id": "client", "role": "viewer", "scope": "products:write"}),
+			entities.PermissionWriteProducts, http.StatusForbidden},
+		{"role-less token without client", encode(map[string]interface{}{"sub": "user", "scope": "products:read"}),
+			entities.PermissionReadProducts, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set("Authorization", "Bearer "+c.token)
+
+		w := httptest.NewRecorder()
+		protected(c.permission).ServeHTTP(w, r)
+		assert.Equal(t, c.status, w.Code, c.name)
+	}
+}
+
+func TestFirstPartyOnly(t *testing.T) {
+	handler := jwtauth.Verifier(tokenAuth)(Authenticator(FirstPartyOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, requestWithRole(t, "viewer"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	_, token, _ := tokenAuth.Encode(map[string]interface{}{
+		"sub":       "user",
+		"role":      "viewer",
+		"client_id": "client",
+		"exp":       time.Now().Add(time.Minute).Unix(),
+	})
+	r := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUserFromContext(t *testing.T) {
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		user   string
+	}{
+		{"first party", map[string]interface{}{"sub": "user", "role": "viewer"}, "user"},
+		{"delegated", map[string]interface{}{"sub": "user", "role": "viewer", "client_id": "client"}, "user"},
+		{"client credentials", map[string]interface{}{"sub": "client", "client_id": "client", "scope": "products:write"}, ""},
+	}
+	for _, c := range cases {
+		c.claims["exp"] = time.Now().Add(time.Minute).Unix()
+		_, token, err := tokenAuth.Encode(c.claims)
+		assert.NoError(t, err)
+
+		var user string
+		handler := jwtauth.Verifier(tokenAuth)(Authenticator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user = UserFromContext(r)
+		})))
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, c.user, user, c.name)
+	}
+}
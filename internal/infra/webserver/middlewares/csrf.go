@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+)
+
+// CSRF requires the double-submitted CSRF token on unsafe requests authenticated by the session
+// cookie. Requests with an Authorization header are not exposed to CSRF and pass through. It goes
+// right after Verifier.
+func CSRF(cookies *session.Cookies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if FromCookie(r) && !session.IsSafeMethod(r.Method) && !cookies.CheckCSRF(r) {
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeCSRFFailed,
+					"missing or invalid "+session.CSRFHeader+" header"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// LoginRedirect takes the place of RejectRevoked and Authenticator on pages a browser navigates to.
+// A GET without a valid, unrevoked token is sent to loginPath with a return_to back to the page
+// instead of getting a 401; other methods still get the problem.
+func LoginRedirect(loginPath string, store revocation.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, err := jwtauth.FromContext(r.Context())
+			signedIn := err == nil && token != nil && jwt.Validate(token) == nil
+			if signedIn {
+				revoked, err := store.IsRevoked(r.Context(), token.JwtID(), token.Subject(), token.IssuedAt())
+				if err != nil {
+					problem.Error(w, r, err)
+					return
+				}
+				signedIn = !revoked
+			}
+
+			if !signedIn {
+				if r.Method != http.MethodGet {
+					problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "sign in to continue"))
+					return
+				}
+				target := loginPath + "?" + url.Values{"return_to": {r.URL.RequestURI()}}.Encode()
+				http.Redirect(w, r, target, http.StatusFound)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginRedirect(t *testing.T) {
+	keys, _ := jwks.NewHMAC("HS256", []byte("secret"))
+	cookies := session.Consent("", true)
+	store := revocation.NewMemory()
+	handler := Verifier(keys, cookies)(LoginRedirect("/oauth/login", store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	_, token, _ := keys.Encode(map[string]interface{}{
+		"jti": "jti-1",
+		"sub": "user",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	request := func(method string, cookie bool) *http.Request {
+		r := httptest.NewRequest(method, "/oauth/authorize?client_id=c&state=s", nil)
+		if cookie {
+			r.AddCookie(&http.Cookie{Name: cookies.Name, Value: token})
+		}
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodGet, true))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodGet, false))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/oauth/login?return_to=%2Foauth%2Fauthorize%3Fclient_id%3Dc%26state%3Ds", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodPost, false))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.NoError(t, store.RevokeToken(context.Background(), "jti-1", "user", time.Now().Add(time.Minute)))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodGet, true))
+	assert.Equal(t, http.StatusFound, w.Code)
+}
@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/caiocp/go-api/internal/infra/webserver/problem"
+	"github.com/go-chi/jwtauth"
+)
+
+// RejectRevoked refuses verified tokens found in the revocation store. It goes right after
+// jwtauth.Verifier; requests without a verified token pass through for Authenticator to handle.
+func RejectRevoked(store revocation.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			revoked, err := store.IsRevoked(r.Context(), token.JwtID(), token.Subject(), token.IssuedAt())
+			if err != nil {
+				problem.Error(w, r, err)
+				return
+			}
+			if revoked {
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeTokenRevoked, "token has been revoked"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
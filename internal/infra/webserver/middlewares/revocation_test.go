@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/infra/revocation"
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectRevoked(t *testing.T) {
+	store := revocation.NewMemory()
+	handler := jwtauth.Verifier(tokenAuth)(RejectRevoked(store)(Authenticator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))))
+
+	request := func(jti string) *http.Request {
+		_, token, _ := tokenAuth.Encode(map[string]interface{}{
+			"jti": jti,
+			"sub": "user",
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request("jti-1"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.NoError(t, store.RevokeToken(context.Background(), "jti-1", "user", time.Now().Add(time.Minute)))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request("jti-1"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request("jti-2"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
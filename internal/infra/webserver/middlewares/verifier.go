@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+type cookieAuthKey struct{}
+
+// Verifier is jwtauth.Verifier for a jwks.KeySet: it looks for a token in the Authorization header
+// or, when cookies is not nil, the session cookie, verifies it against the key named by its kid and
+// stores the result with jwtauth.NewContext, so jwtauth.FromContext and Authenticator work unchanged.
+// The header wins when a request carries both.
+func Verifier(keys *jwks.KeySet, cookies *session.Cookies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := jwtauth.TokenFromHeader(r)
+			ctx := r.Context()
+			if tokenString == "" && cookies != nil {
+				tokenString = cookies.AccessToken(r)
+				ctx = context.WithValue(ctx, cookieAuthKey{}, tokenString != "")
+			}
+
+			token, err := verifyToken(keys, tokenString)
+			next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(ctx, token, err)))
+		})
+	}
+}
+
+// FromCookie reports whether the token of the request came from the session cookie.
+func FromCookie(r *http.Request) bool {
+	fromCookie, _ := r.Context().Value(cookieAuthKey{}).(bool)
+	return fromCookie
+}
+
+func verifyToken(keys *jwks.KeySet, tokenString string) (jwt.Token, error) {
+	if tokenString == "" {
+		return nil, jwtauth.ErrNoTokenFound
+	}
+
+	token, err := keys.Decode(tokenString)
+	if err != nil {
+		return nil, jwtauth.ErrUnauthorized
+	}
+
+	if err := jwt.Validate(token); err != nil {
+		return token, jwtauth.ErrorReason(err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caiocp/go-api/internal/infra/jwks"
+	"github.com/caiocp/go-api/internal/infra/webserver/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifier(t *testing.T) {
+	keys, err := jwks.NewHMAC("HS256", []byte("secret"))
+	assert.NoError(t, err)
+	other, _ := jwks.NewHMAC("HS256", []byte("other"))
+
+	handler := Verifier(keys, nil)(Authenticator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	cases := []struct {
+		keys   *jwks.KeySet
+		exp    time.Duration
+		status int
+	}{
+		{keys, time.Minute, http.StatusNoContent},
+		{keys, -time.Minute, http.StatusUnauthorized},
+		{other, time.Minute, http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		_, token, err := c.keys.Encode(map[string]interface{}{"sub": "user", "exp": time.Now().Add(c.exp).Unix()})
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, c.status, w.Code)
+	}
+}
+
+func TestVerifierSessionCookie(t *testing.T) {
+	keys, _ := jwks.NewHMAC("HS256", []byte("secret"))
+	cookies, _ := session.New("session", "", true, "strict")
+	_, token, _ := keys.Encode(map[string]interface{}{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	withCookies := Verifier(keys, cookies)(CSRF(cookies)(Authenticator(ok)))
+	withoutCookies := Verifier(keys, nil)(Authenticator(ok))
+
+	request := func(method, csrf string) *http.Request {
+		r := httptest.NewRequest(method, "/products", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: token})
+		r.AddCookie(&http.Cookie{Name: "csrf_token", Value: "csrf"})
+		if csrf != "" {
+			r.Header.Set(session.CSRFHeader, csrf)
+		}
+		return r
+	}
+
+	cases := []struct {
+		name    string
+		handler http.Handler
+		request *http.Request
+		status  int
+	}{
+		{"cookie read", withCookies, request(http.MethodGet, ""), http.StatusNoContent},
+		{"cookie write with csrf", withCookies, request(http.MethodPost, "csrf"), http.StatusNoContent},
+		{"cookie write without csrf", withCookies, request(http.MethodPost, ""), http.StatusForbidden},
+		{"cookie write with wrong csrf", withCookies, request(http.MethodDelete, "forged"), http.StatusForbidden},
+		{"cookies disabled", withoutCookies, request(http.MethodGet, ""), http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		c.handler.ServeHTTP(w, c.request)
+		assert.Equal(t, c.status, w.Code, c.name)
+	}
+
+	// a bearer token needs no CSRF token even when a session cookie is present
+	r := request(http.MethodPost, "")
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	withCookies.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
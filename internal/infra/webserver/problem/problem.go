@@ -0,0 +1,167 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/pkg/query"
+	"github.com/caiocp/go-api/pkg/validator"
+	"gorm.io/gorm"
+)
+
+const ContentType = "application/problem+json"
+
+// Machine-readable error codes returned in the "code" member.
+const (
+	CodeInvalidJSON          = "invalid_json"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInvalidPatch         = "invalid_patch"
+	CodePatchConflict        = "patch_conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeInvalidParam         = "invalid_parameter"
+	CodeValidation           = "validation_failed"
+	CodeNotFound             = "not_found"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeTokenRevoked         = "token_revoked"
+	CodeCSRFFailed           = "csrf_failed"
+	CodeTooManyAttempts      = "too_many_attempts"
+	CodeTimeout              = "timeout"
+	CodeInternalError        = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details object extended with a machine-readable code and
+// optional field-level errors.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+} // @name Problem
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+} // @name FieldError
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+func New(status int, code, detail string, fieldErrors ...FieldError) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: fieldErrors,
+	}
+}
+
+type mapping struct {
+	err    error
+	status int
+	code   string
+	field  string
+}
+
+// mappings translates domain errors into problems. Errors tied to a request field also produce a
+// field-level entry so clients can show the message next to the input.
+var mappings = []mapping{
+	{entities.ErrIDIsRequired, http.StatusBadRequest, "id_required", "id"},
+	{entities.ErrInvalidID, http.StatusBadRequest, "invalid_id", "id"},
+	{entities.ErrNameIsRequired, http.StatusBadRequest, "name_required", "name"},
+	{entities.ErrPriceIsRequired, http.StatusBadRequest, "price_required", "price"},
+	{entities.ErrInvalidPrice, http.StatusBadRequest, "invalid_price", "price"},
+	{entities.ErrProductModified, http.StatusPreconditionFailed, CodePreconditionFailed, ""},
+	{entities.ErrInvalidOwner, http.StatusBadRequest, "invalid_owner", "owner_id"},
+	{entities.ErrInvalidRole, http.StatusBadRequest, "invalid_role", "role"},
+	{entities.ErrInvalidScope, http.StatusBadRequest, "invalid_scope", "scopes"},
+	{entities.ErrInvalidExpiresAt, http.StatusBadRequest, "invalid_expires_at", "expires_at"},
+	{entities.ErrInvalidGrantType, http.StatusBadRequest, "invalid_grant_type", "grant_types"},
+	{entities.ErrInvalidRedirectURI, http.StatusBadRequest, "invalid_redirect_uri", "redirect_uris"},
+	{entities.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials", ""},
+	{entities.ErrEmailTaken, http.StatusConflict, "email_taken", ""},
+	{entities.ErrEmailNotVerified, http.StatusForbidden, "email_not_verified", ""},
+	{entities.ErrInvalidVerificationToken, http.StatusBadRequest, "invalid_verification_token", "token"},
+	{entities.ErrTwoFactorAlreadyEnabled, http.StatusConflict, "two_factor_already_enabled", ""},
+	{entities.ErrTwoFactorNotEnrolled, http.StatusConflict, "two_factor_not_enrolled", ""},
+	{entities.ErrInvalidTwoFactorCode, http.StatusBadRequest, "invalid_two_factor_code", "code"},
+	{entities.ErrTwoFactorChallenge, http.StatusUnauthorized, "two_factor_failed", ""},
+	{entities.ErrPasswordTooShort, http.StatusBadRequest, "password_too_short", ""},
+	{entities.ErrPasswordBreached, http.StatusBadRequest, "password_breached", ""},
+	{entities.ErrIncorrectPassword, http.StatusBadRequest, "incorrect_password", "current_password"},
+	{entities.ErrInvalidPasswordResetToken, http.StatusBadRequest, "invalid_reset_token", "token"},
+	{entities.ErrInvalidRefreshToken, http.StatusUnauthorized, "invalid_refresh_token", ""},
+	{entities.ErrRefreshTokenExpired, http.StatusUnauthorized, "refresh_token_expired", ""},
+	{entities.ErrRefreshTokenReused, http.StatusUnauthorized, "refresh_token_reused", ""},
+	{gorm.ErrRecordNotFound, http.StatusNotFound, CodeNotFound, ""},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout, ""},
+}
+
+// FromError maps err to a problem. Problems pass through untouched and unknown errors become a
+// 500 that does not leak the underlying message.
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var violations validator.Errors
+	if errors.As(err, &violations) {
+		fieldErrors := make([]FieldError, len(violations))
+		for i, v := range violations {
+			fieldErrors[i] = FieldError{Field: v.Field, Code: v.Rule, Message: v.Message}
+		}
+		return New(http.StatusBadRequest, CodeValidation, "the request contains invalid fields", fieldErrors...)
+	}
+
+	var params query.Errors
+	if errors.As(err, &params) {
+		fieldErrors := make([]FieldError, len(params))
+		for i, e := range params {
+			fieldErrors[i] = FieldError{Field: e.Param, Code: CodeInvalidParam, Message: e.Message}
+		}
+		return New(http.StatusBadRequest, CodeInvalidParam, "the query contains invalid parameters", fieldErrors...)
+	}
+
+	for _, m := range mappings {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+		if m.field == "" {
+			return New(m.status, m.code, m.err.Error())
+		}
+		return New(m.status, CodeValidation, "the request contains invalid fields",
+			FieldError{Field: m.field, Code: m.code, Message: m.err.Error()})
+	}
+
+	return New(http.StatusInternalServerError, CodeInternalError, "an unexpected error occurred")
+}
+
+// Write renders p as application/problem+json.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	body := *p
+	if body.Instance == "" {
+		body.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(body.Status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Error maps err with FromError and renders the result.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, FromError(err))
+}
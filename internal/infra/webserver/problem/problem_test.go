@@ -0,0 +1,85 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caiocp/go-api/internal/entities"
+	"github.com/caiocp/go-api/pkg/query"
+	"github.com/caiocp/go-api/pkg/validator"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestFromErrorMapsDomainErrors(t *testing.T) {
+	p := FromError(entities.ErrInvalidPrice)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, CodeValidation, p.Code)
+	assert.Equal(t, []FieldError{{Field: "price", Code: "invalid_price", Message: "invalid price"}}, p.Errors)
+
+	p = FromError(entities.ErrEmailTaken)
+	assert.Equal(t, http.StatusConflict, p.Status)
+	assert.Equal(t, "email_taken", p.Code)
+
+	p = FromError(fmt.Errorf("find product: %w", gorm.ErrRecordNotFound))
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, CodeNotFound, p.Code)
+	assert.Empty(t, p.Errors)
+}
+
+func TestFromErrorHidesUnknownErrors(t *testing.T) {
+	p := FromError(errors.New("dial tcp 10.0.0.1:5432: connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.Equal(t, CodeInternalError, p.Code)
+	assert.NotContains(t, p.Detail, "10.0.0.1")
+}
+
+func TestFromErrorPassesProblemsThrough(t *testing.T) {
+	original := New(http.StatusConflict, "conflict", "already exists")
+	assert.Same(t, original, FromError(fmt.Errorf("wrapped: %w", original)))
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+
+	Error(w, r, entities.ErrNameIsRequired)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	var body Problem
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "about:blank", body.Type)
+	assert.Equal(t, "Bad Request", body.Title)
+	assert.Equal(t, "/products", body.Instance)
+	assert.Equal(t, "name", body.Errors[0].Field)
+}
+
+func TestFromErrorMapsValidationErrors(t *testing.T) {
+	p := FromError(validator.Errors{
+		{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		{Field: "password", Rule: "min", Message: "password must be at least 6 characters"},
+	})
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, CodeValidation, p.Code)
+	assert.Len(t, p.Errors, 2)
+	assert.Equal(t, "email", p.Errors[0].Field)
+	assert.Equal(t, "min", p.Errors[1].Code)
+}
+
+func TestFromErrorMapsQueryErrors(t *testing.T) {
+	p := FromError(query.Errors{
+		{Param: "price_min", Message: "price_min must be a number"},
+		{Param: "sort", Message: `cannot sort by "owner"`},
+	})
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, CodeInvalidParam, p.Code)
+	assert.Len(t, p.Errors, 2)
+	assert.Equal(t, "price_min", p.Errors[0].Field)
+	assert.Equal(t, "sort", p.Errors[1].Field)
+}
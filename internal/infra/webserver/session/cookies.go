@@ -0,0 +1,179 @@
+// Package session carries first-party tokens in cookies for browser clients. The access token and
+// the refresh token live in HttpOnly cookies; a third, script-readable cookie holds the CSRF token
+// that the client echoes in the X-CSRF-Token header on unsafe requests (double-submit).
+package session
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// CSRFHeader carries the double-submitted CSRF token.
+	CSRFHeader = "X-CSRF-Token"
+
+	csrfCookie    = "csrf_token"
+	refreshCookie = "refresh_token"
+
+	// refreshPath limits the refresh cookie to the endpoints that rotate and revoke it.
+	refreshPath = "/users"
+
+	consentCookie = "oauth_session"
+	// consentPath limits the consent cookie to the OAuth2 login and consent pages.
+	consentPath = "/oauth"
+
+	// LoginCSRFField is the sign-in form field that echoes the login CSRF cookie.
+	LoginCSRFField = "csrf_token"
+
+	loginCSRFCookie = "oauth_login_csrf"
+	loginPath       = "/oauth/login"
+)
+
+var ErrInvalidSameSite = errors.New("SameSite must be one of lax, strict or none")
+
+type Cookies struct {
+	Name     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// New configures session cookies. sameSite is lax, strict or none.
+func New(name, domain string, secure bool, sameSite string) (*Cookies, error) {
+	modes := map[string]http.SameSite{
+		"lax":    http.SameSiteLaxMode,
+		"strict": http.SameSiteStrictMode,
+		"none":   http.SameSiteNoneMode,
+	}
+
+	mode, ok := modes[strings.ToLower(sameSite)]
+	if !ok {
+		return nil, ErrInvalidSameSite
+	}
+
+	return &Cookies{Name: name, Domain: domain, Secure: secure, SameSite: mode}, nil
+}
+
+// Consent configures the cookie that signs a browser in to the OAuth2 consent page. Client
+// applications send the browser there with a cross-site redirect, which does not carry a SameSite=Strict
+// cookie, so this one is SameSite=Lax and only sent to /oauth. It works whether or not session cookies
+// are enabled.
+func Consent(domain string, secure bool) *Cookies {
+	return &Cookies{Name: consentCookie, Domain: domain, Secure: secure, SameSite: http.SameSiteLaxMode}
+}
+
+// IssueConsent sets the consent cookie to accessToken.
+func (c *Cookies) IssueConsent(w http.ResponseWriter, accessToken string, ttl time.Duration) {
+	http.SetCookie(w, c.cookie(c.Name, accessToken, consentPath, ttl, true))
+}
+
+// IssueLoginCSRF sets a CSRF cookie for the sign-in page and returns the token for its form, so that a
+// third-party page cannot sign the browser in to an account of its choosing. The cookie lasts for the
+// browser session.
+func (c *Cookies) IssueLoginCSRF(w http.ResponseWriter) (string, error) {
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, c.cookie(loginCSRFCookie, csrf, loginPath, 0, true))
+	return csrf, nil
+}
+
+// CheckLoginCSRF reports whether the LoginCSRFField of the parsed form matches the login CSRF cookie.
+func (c *Cookies) CheckLoginCSRF(r *http.Request) bool {
+	cookie := c.value(r, loginCSRFCookie)
+	field := r.PostForm.Get(LoginCSRFField)
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(field)) == 1
+}
+
+// Issue sets the session cookies and returns the new CSRF token.
+func (c *Cookies) Issue(w http.ResponseWriter, accessToken string, accessTTL time.Duration, refreshToken string, refreshTTL time.Duration) (string, error) {
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, c.cookie(c.Name, accessToken, "/", accessTTL, true))
+	http.SetCookie(w, c.cookie(refreshCookie, refreshToken, refreshPath, refreshTTL, true))
+	http.SetCookie(w, c.cookie(csrfCookie, csrf, "/", refreshTTL, false))
+
+	return csrf, nil
+}
+
+// Clear expires every session cookie.
+func (c *Cookies) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, c.cookie(c.Name, "", "/", -1, true))
+	http.SetCookie(w, c.cookie(refreshCookie, "", refreshPath, -1, true))
+	http.SetCookie(w, c.cookie(csrfCookie, "", "/", -1, false))
+}
+
+// AccessToken returns the access token of the session cookie, or an empty string.
+func (c *Cookies) AccessToken(r *http.Request) string {
+	return c.value(r, c.Name)
+}
+
+// RefreshToken returns the refresh token of the session, or an empty string.
+func (c *Cookies) RefreshToken(r *http.Request) string {
+	return c.value(r, refreshCookie)
+}
+
+// CheckCSRF reports whether the X-CSRF-Token header matches the CSRF cookie. A cross-site page can
+// make the browser send the cookie but can neither read it nor set the header.
+func (c *Cookies) CheckCSRF(r *http.Request) bool {
+	cookie := c.value(r, csrfCookie)
+	header := r.Header.Get(CSRFHeader)
+
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+// IsSafeMethod reports whether method cannot change state and so needs no CSRF token.
+func IsSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+func newCSRFToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func (c *Cookies) cookie(name, value, path string, ttl time.Duration, httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+		SameSite: c.SameSite,
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+
+	return cookie
+}
+
+func (c *Cookies) value(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
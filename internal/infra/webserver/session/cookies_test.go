@@ -0,0 +1,118 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRejectsUnknownSameSite(t *testing.T) {
+	_, err := New("session", "", true, "sometimes")
+	assert.Equal(t, ErrInvalidSameSite, err)
+
+	cookies, err := New("session", "", true, "Strict")
+	assert.NoError(t, err)
+	assert.Equal(t, http.SameSiteStrictMode, cookies.SameSite)
+}
+
+func TestIssueAndRead(t *testing.T) {
+	cookies, _ := New("session", "example.com", true, "lax")
+
+	w := httptest.NewRecorder()
+	csrf, err := cookies.Issue(w, "access", time.Minute, "refresh", time.Hour)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, csrf)
+
+	issued := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		issued[c.Name] = c
+	}
+	assert.Len(t, issued, 3)
+	assert.True(t, issued["session"].HttpOnly)
+	assert.True(t, issued["session"].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, issued["session"].SameSite)
+	assert.Equal(t, 60, issued["session"].MaxAge)
+	assert.Equal(t, "/users", issued["refresh_token"].Path)
+	assert.True(t, issued["refresh_token"].HttpOnly)
+	assert.False(t, issued["csrf_token"].HttpOnly)
+	assert.Equal(t, csrf, issued["csrf_token"].Value)
+
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+	for _, c := range issued {
+		r.AddCookie(c)
+	}
+	assert.Equal(t, "access", cookies.AccessToken(r))
+	assert.Equal(t, "refresh", cookies.RefreshToken(r))
+	assert.False(t, cookies.CheckCSRF(r))
+
+	r.Header.Set(CSRFHeader, "forged")
+	assert.False(t, cookies.CheckCSRF(r))
+
+	r.Header.Set(CSRFHeader, csrf)
+	assert.True(t, cookies.CheckCSRF(r))
+}
+
+func TestClear(t *testing.T) {
+	cookies, _ := New("session", "", true, "strict")
+
+	w := httptest.NewRecorder()
+	cookies.Clear(w)
+
+	for _, c := range w.Result().Cookies() {
+		assert.Empty(t, c.Value)
+		assert.Equal(t, -1, c.MaxAge)
+	}
+	assert.Len(t, w.Result().Cookies(), 3)
+}
+
+func TestCheckCSRFWithoutCookie(t *testing.T) {
+	cookies, _ := New("session", "", true, "strict")
+
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+	r.Header.Set(CSRFHeader, "")
+	assert.False(t, cookies.CheckCSRF(r))
+}
+
+func TestIssueConsent(t *testing.T) {
+	cookies := Consent("example.com", true)
+
+	w := httptest.NewRecorder()
+	cookies.IssueConsent(w, "access", time.Minute)
+
+	issued := w.Result().Cookies()
+	assert.Len(t, issued, 1)
+	assert.Equal(t, "oauth_session", issued[0].Name)
+	assert.Equal(t, "/oauth", issued[0].Path)
+	assert.True(t, issued[0].HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, issued[0].SameSite)
+
+	r := httptest.NewRequest(http.MethodGet, "/oauth/authorize", nil)
+	r.AddCookie(issued[0])
+	assert.Equal(t, "access", cookies.AccessToken(r))
+}
+
+func TestLoginCSRF(t *testing.T) {
+	cookies := Consent("", true)
+	w := httptest.NewRecorder()
+	csrf, err := cookies.IssueLoginCSRF(w)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, csrf)
+
+	issued := w.Result().Cookies()
+	assert.Len(t, issued, 1)
+	assert.Equal(t, "/oauth/login", issued[0].Path)
+	assert.True(t, issued[0].HttpOnly)
+
+	r := httptest.NewRequest(http.MethodPost, "/oauth/login", nil)
+	r.PostForm = url.Values{LoginCSRFField: {csrf}}
+	assert.False(t, cookies.CheckLoginCSRF(r))
+
+	r.AddCookie(issued[0])
+	assert.True(t, cookies.CheckLoginCSRF(r))
+	r.PostForm.Set(LoginCSRFField, "forged")
+	assert.False(t, cookies.CheckLoginCSRF(r))
+}
@@ -0,0 +1,44 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergePatch applies the JSON Merge Patch patch to the JSON document doc. Members of an object patch
+// replace those of doc, recursively for objects, and null members remove them; any other patch
+// replaces doc entirely.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = merge(t[key], value)
+	}
+
+	return t
+}
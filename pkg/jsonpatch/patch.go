@@ -0,0 +1,287 @@
+// Package jsonpatch applies JSON Patch (RFC 6902) and JSON Merge Patch (RFC 7396) documents to JSON
+// values.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch means the patch document itself is malformed.
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrPathNotFound means an operation refers to a location the target does not have.
+	ErrPathNotFound = errors.New("patch path does not exist")
+	// ErrTestFailed means a test operation did not match, so nothing was applied.
+	ErrTestFailed = errors.New("patch test operation failed")
+)
+
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies the JSON Patch patch to the JSON document doc. Operations run in order and the
+// patch is all or nothing: on error doc is left as it was and no result is returned.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		target, err = op.apply(target)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: %s without path", ErrInvalidPatch, op.Op)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %s without value", ErrInvalidPatch, op.Op)
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			doc, _, err = remove(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("%w at %s", ErrTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %s without from", ErrInvalidPatch, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		if op.Op == "move" {
+			if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+				return nil, fmt.Errorf("%w: cannot move %s into itself", ErrInvalidPatch, *op.From)
+			}
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			value = deepCopy(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: pointer %q does not start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// arrayIndex parses an array reference token. "-" stands for the end of the array and is only
+// allowed when appending.
+func arrayIndex(token string, length int, appending bool) (int, error) {
+	if token == "-" && appending {
+		return length, nil
+	}
+	if token == "" || token != "0" && token[0] == '0' {
+		return 0, ErrPathNotFound
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || i == length && !appending {
+		return 0, ErrPathNotFound
+	}
+
+	return i, nil
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			node = child
+		case []interface{}:
+			i, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+
+	return node, nil
+}
+
+// add sets value at path, inserting into arrays, and returns the updated node.
+func add(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, last := path[0], len(path) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if last {
+			n[token] = value
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		child, err := add(child, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n), last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		n[i], err = add(n[i], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+// remove deletes the value at path and returns the updated node along with the removed value.
+func remove(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	token, last := path[0], len(path) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, ErrPathNotFound
+		}
+		if last {
+			delete(n, token)
+			return n, child, nil
+		}
+		child, removed, err := remove(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[token] = child
+		return n, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		var removed interface{}
+		n[i], removed, err = remove(n[i], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		return n, removed, nil
+	default:
+		return nil, nil, ErrPathNotFound
+	}
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			c[key] = deepCopy(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = deepCopy(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
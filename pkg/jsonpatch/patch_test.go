@@ -0,0 +1,83 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the examples of RFC 6902 appendix A
+func TestApplyRFC6902Examples(t *testing.T) {
+	cases := []struct {
+		name, doc, patch, want string
+	}{
+		{"add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`},
+		{"test", `{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add nested object", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"child":{"grandchild":{}},"foo":"bar"}`},
+		{"ignore unknown members", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add to nonexistent target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ""},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"comparing strings and numbers", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, ""},
+		{"add array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"copy", `{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+			`{"baz":{"bar":2},"foo":{"bar":1}}`},
+		{"add null", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"baz":null,"foo":"bar"}`},
+		{"add root", `{"foo":"bar"}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{"replace root", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`},
+		{"replace last array element", `{"foo":[1,2]}`, `[{"op":"replace","path":"/foo/1","value":3}]`, `{"foo":[1,3]}`},
+	}
+
+	for _, c := range cases {
+		got, err := Apply([]byte(c.doc), []byte(c.patch))
+		if c.want == "" {
+			assert.Error(t, err, c.name)
+			continue
+		}
+		assert.NoError(t, err, c.name)
+		assert.JSONEq(t, c.want, string(got), c.name)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	doc := []byte(`{"foo":["bar"],"n":1}`)
+
+	for patch, want := range map[string]error{
+		`{"op":"add"}`:                                   ErrInvalidPatch,
+		`[{"op":"add","path":"/x"}]`:                     ErrInvalidPatch,
+		`[{"op":"jump","path":"/x"}]`:                    ErrInvalidPatch,
+		`[{"op":"add","path":"x","value":1}]`:            ErrInvalidPatch,
+		`[{"op":"move","path":"/x"}]`:                    ErrInvalidPatch,
+		`[{"op":"move","from":"/foo","path":"/foo/0"}]`:  ErrInvalidPatch,
+		`[{"op":"remove","path":"/missing"}]`:            ErrPathNotFound,
+		`[{"op":"replace","path":"/missing","value":1}]`: ErrPathNotFound,
+		`[{"op":"remove","path":"/foo/1"}]`:              ErrPathNotFound,
+		`[{"op":"remove","path":"/foo/-"}]`:              ErrPathNotFound,
+		`[{"op":"add","path":"/foo/01","value":1}]`:      ErrPathNotFound,
+		`[{"op":"add","path":"/foo/2","value":1}]`:       ErrPathNotFound,
+		`[{"op":"test","path":"/n","value":2}]`:          ErrTestFailed,
+	} {
+		_, err := Apply(doc, []byte(patch))
+		assert.ErrorIs(t, err, want, patch)
+	}
+}
+
+func TestApplyIsAllOrNothing(t *testing.T) {
+	doc := []byte(`{"name":"a","price":1}`)
+
+	got, err := Apply(doc, []byte(`[{"op":"replace","path":"/name","value":"b"},{"op":"test","path":"/price","value":2}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+	assert.Nil(t, got)
+	assert.JSONEq(t, `{"name":"a","price":1}`, string(doc))
+}
@@ -0,0 +1,156 @@
+// Package password hashes and verifies user passwords. New hashes are argon2id by default, stored in
+// the PHC string format, and bcrypt hashes from before the switch keep verifying so they can be
+// upgraded at the next successful login.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidHash       = errors.New("invalid password hash")
+	ErrInvalidParameters = errors.New("invalid argon2id parameters")
+)
+
+// Hasher produces and checks password hashes.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash, whichever supported algorithm produced it.
+	Verify(hash, password string) bool
+	// NeedsRehash reports whether hash was made with another algorithm or other parameters than the
+	// ones Hash uses now.
+	NeedsRehash(hash string) bool
+}
+
+// Argon2id hashes with argon2id. Memory is in KiB.
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2id follows the OWASP recommendation of 19 MiB of memory and two passes.
+var DefaultArgon2id = Argon2id{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var encoding = base64.RawStdEncoding
+
+// Validate reports parameters argon2 cannot work with, which would make Hash panic.
+func (a Argon2id) Validate() error {
+	switch {
+	case a.Iterations == 0:
+		return fmt.Errorf("%w: iterations must be at least 1", ErrInvalidParameters)
+	case a.Parallelism == 0:
+		return fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidParameters)
+	case a.KeyLength == 0:
+		return fmt.Errorf("%w: key length must be at least 1", ErrInvalidParameters)
+	}
+
+	return nil
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(hash, password string) bool {
+	return Verify(hash, password)
+}
+
+func (a Argon2id) NeedsRehash(hash string) bool {
+	params, _, _, err := parseArgon2id(hash)
+	return err != nil || params != a
+}
+
+// Bcrypt hashes with bcrypt, for deployments that have to stay on it.
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(hash, password string) bool {
+	return Verify(hash, password)
+}
+
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.Cost
+}
+
+// Verify reports whether password matches an argon2id or a bcrypt hash.
+func Verify(hash, password string) bool {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// parseArgon2id splits a hash of the form $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>.
+func parseArgon2id(hash string) (Argon2id, []byte, []byte, error) {
+	var params Argon2id
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil ||
+		params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := encoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := encoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
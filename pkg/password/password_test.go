@@ -0,0 +1,87 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	hash, err := DefaultArgon2id.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"))
+
+	assert.True(t, DefaultArgon2id.Verify(hash, "correct horse"))
+	assert.False(t, DefaultArgon2id.Verify(hash, "wrong horse"))
+	assert.False(t, DefaultArgon2id.NeedsRehash(hash))
+
+	other, err := DefaultArgon2id.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other, "every hash gets its own salt")
+}
+
+func TestArgon2idVerifiesBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	assert.True(t, DefaultArgon2id.Verify(string(legacy), "correct horse"))
+	assert.False(t, DefaultArgon2id.Verify(string(legacy), "wrong horse"))
+	assert.True(t, DefaultArgon2id.NeedsRehash(string(legacy)))
+}
+
+func TestArgon2idNeedsRehashOnNewParameters(t *testing.T) {
+	hash, err := DefaultArgon2id.Hash("correct horse")
+	assert.NoError(t, err)
+
+	stronger := DefaultArgon2id
+	stronger.Iterations = 3
+	assert.True(t, stronger.NeedsRehash(hash))
+	assert.True(t, stronger.Verify(hash, "correct horse"), "old parameters still verify")
+}
+
+func TestArgon2idValidate(t *testing.T) {
+	assert.NoError(t, DefaultArgon2id.Validate())
+
+	for name, params := range map[string]Argon2id{
+		"iterations":  {Memory: 19 * 1024, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		"parallelism": {Memory: 19 * 1024, Iterations: 2, Parallelism: 0, SaltLength: 16, KeyLength: 32},
+		"key length":  {Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 0},
+	} {
+		err := params.Validate()
+		assert.ErrorIs(t, err, ErrInvalidParameters, name)
+		assert.Contains(t, err.Error(), name, name)
+	}
+}
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	hasher := Bcrypt{Cost: bcrypt.MinCost}
+	hash, err := hasher.Hash("correct horse")
+	assert.NoError(t, err)
+
+	assert.True(t, hasher.Verify(hash, "correct horse"))
+	assert.False(t, hasher.NeedsRehash(hash))
+	assert.True(t, Bcrypt{Cost: bcrypt.MinCost + 1}.NeedsRehash(hash))
+
+	argon, err := DefaultArgon2id.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, hasher.Verify(argon, "correct horse"))
+	assert.True(t, hasher.NeedsRehash(argon))
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plain",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=0$c2FsdA$a2V5",
+	} {
+		assert.False(t, Verify(hash, ""), hash)
+		assert.True(t, DefaultArgon2id.NeedsRehash(hash), hash)
+	}
+}
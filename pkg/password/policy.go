@@ -0,0 +1,60 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrBreached = errors.New("password appears in a list of breached passwords")
+)
+
+// Policy decides which new passwords are acceptable.
+type Policy struct {
+	MinLength int
+
+	breached map[string]struct{}
+}
+
+// LoadBreached reads a list of known breached passwords, one per line, that Check refuses from then
+// on. Blank lines and lines starting with # are skipped.
+func (p *Policy) LoadBreached(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	p.breached = breached
+	return nil
+}
+
+// Check returns ErrTooShort or ErrBreached when password may not be used.
+func (p *Policy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return ErrTooShort
+	}
+	if _, ok := p.breached[password]; ok {
+		return ErrBreached
+	}
+
+	return nil
+}
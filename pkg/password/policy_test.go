@@ -0,0 +1,35 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyMinLength(t *testing.T) {
+	policy := Policy{MinLength: 8}
+
+	assert.ErrorIs(t, policy.Check("1234567"), ErrTooShort)
+	assert.NoError(t, policy.Check("12345678"))
+	assert.ErrorIs(t, policy.Check("äöüäöüä"), ErrTooShort, "length counts characters, not bytes")
+}
+
+func TestPolicyBreached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# top passwords\npassword1\r\n\nletmein123\n"), 0o600))
+
+	policy := Policy{MinLength: 8}
+	assert.NoError(t, policy.LoadBreached(path))
+
+	assert.ErrorIs(t, policy.Check("password1"), ErrBreached)
+	assert.ErrorIs(t, policy.Check("letmein123"), ErrBreached)
+	assert.NoError(t, policy.Check("# top passwords"))
+	assert.NoError(t, policy.Check("Password1"))
+}
+
+func TestPolicyLoadBreachedMissingFile(t *testing.T) {
+	policy := Policy{}
+	assert.Error(t, policy.LoadBreached(filepath.Join(t.TempDir(), "missing.txt")))
+}
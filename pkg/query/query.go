@@ -0,0 +1,258 @@
+// Package query turns the query string of a list request into a Spec: which page to return, how to
+// filter and how to sort. Endpoints describe what they accept with a Builder, so every parameter and
+// sort field reaching the database has been whitelisted.
+package query
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Op is a comparison a filter applies between a field and its value.
+type Op string
+
+const (
+	Eq       Op = "eq"
+	Contains Op = "contains"
+	Prefix   Op = "prefix"
+	Gte      Op = "gte"
+	Lte      Op = "lte"
+	Lt       Op = "lt"
+)
+
+// Kind is the type a filter parameter is parsed as.
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Time
+)
+
+// Filter restricts a list to the rows whose Field compares to Value with Op.
+type Filter struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+// Order sorts a list by Field, descending when Desc is set.
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Spec is a parsed list request. Page starts at 1 and a zero Limit means no limit.
+type Spec struct {
+	Page    int
+	Limit   int
+	Filters []Filter
+	Sort    []Order
+}
+
+// Offset is the number of rows before the first one of the page.
+func (s Spec) Offset() int {
+	if s.Page < 1 || s.Limit == 0 {
+		return 0
+	}
+
+	return (s.Page - 1) * s.Limit
+}
+
+// TotalPages is the number of pages needed to show total rows, at least one.
+func (s Spec) TotalPages(total int64) int {
+	if s.Limit == 0 || total == 0 {
+		return 1
+	}
+
+	return int((total + int64(s.Limit) - 1) / int64(s.Limit))
+}
+
+// FieldError describes one query parameter that could not be used.
+type FieldError struct {
+	Param   string
+	Message string
+}
+
+// Errors collects every invalid parameter found in a single Parse call.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+type filterParam struct {
+	param string
+	field string
+	op    Op
+	kind  Kind
+}
+
+// Builder describes the parameters a list endpoint accepts. The zero value accepts none and returns
+// every row; set it up with the chained methods and share it between requests.
+type Builder struct {
+	defaultLimit int
+	maxLimit     int
+	optional     bool
+	filters      []filterParam
+	sortFields   map[string]string
+	defaultSort  []Order
+}
+
+// NewBuilder returns a builder paginating by 10 rows, with at most 100 per page.
+func NewBuilder() *Builder {
+	return &Builder{defaultLimit: 10, maxLimit: 100, sortFields: make(map[string]string)}
+}
+
+// Paginate sets the page size used when limit is absent and the largest one a client may ask for.
+func (b *Builder) Paginate(defaultLimit, maxLimit int) *Builder {
+	b.defaultLimit = defaultLimit
+	b.maxLimit = maxLimit
+	return b
+}
+
+// OptionalPaging returns every row to requests that send neither page nor limit, with a zero Limit,
+// for endpoints that were not paginated before.
+func (b *Builder) OptionalPaging() *Builder {
+	b.optional = true
+	return b
+}
+
+// Filter accepts the parameter param, parsed as kind and compared to field with op. A Time upper
+// bound given as a bare date (2006-01-02) covers that whole day.
+func (b *Builder) Filter(param, field string, op Op, kind Kind) *Builder {
+	b.filters = append(b.filters, filterParam{param: param, field: field, op: op, kind: kind})
+	return b
+}
+
+// SortBy lets clients sort by name, which orders by field.
+func (b *Builder) SortBy(name, field string) *Builder {
+	if b.sortFields == nil {
+		b.sortFields = make(map[string]string)
+	}
+	b.sortFields[name] = field
+	return b
+}
+
+// DefaultSort is the order used when the request has no sort parameter, in the syntax of the
+// parameter. It panics on fields that were not allowed with SortBy, as that is a programming error.
+func (b *Builder) DefaultSort(sort string) *Builder {
+	orders, errs := b.parseSort(sort)
+	if errs != nil {
+		panic("query: " + errs.Error())
+	}
+	b.defaultSort = orders
+	return b
+}
+
+// Parse builds a Spec from values. It reads page, limit and sort, a comma separated list of the
+// names allowed with SortBy where a leading - sorts descending, plus the filter parameters. Absent
+// parameters are left out; malformed ones are all reported in an Errors value.
+func (b *Builder) Parse(values url.Values) (Spec, error) {
+	var errs Errors
+	spec := Spec{Page: 1, Limit: b.defaultLimit, Sort: b.defaultSort}
+	if b.optional && values.Get("page") == "" && values.Get("limit") == "" {
+		spec.Limit = 0
+	}
+
+	if page := values.Get("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			errs = append(errs, FieldError{"page", "page must be a positive integer"})
+		}
+		spec.Page = n
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			errs = append(errs, FieldError{"limit", "limit must be a positive integer"})
+		} else if b.maxLimit > 0 && n > b.maxLimit {
+			errs = append(errs, FieldError{"limit", fmt.Sprintf("limit must be at most %d", b.maxLimit)})
+		}
+		spec.Limit = n
+	}
+
+	for _, f := range b.filters {
+		raw := values.Get(f.param)
+		if raw == "" {
+			continue
+		}
+
+		filter, err := f.parse(raw)
+		if err != nil {
+			errs = append(errs, FieldError{f.param, err.Error()})
+			continue
+		}
+		spec.Filters = append(spec.Filters, filter)
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		orders, sortErrs := b.parseSort(sort)
+		errs = append(errs, sortErrs...)
+		spec.Sort = orders
+	}
+
+	if errs != nil {
+		return Spec{}, errs
+	}
+
+	return spec, nil
+}
+
+func (b *Builder) parseSort(sort string) ([]Order, Errors) {
+	var orders []Order
+	var errs Errors
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+
+		field, ok := b.sortFields[name]
+		if !ok {
+			errs = append(errs, FieldError{"sort", fmt.Sprintf("cannot sort by %q", name)})
+			continue
+		}
+		orders = append(orders, Order{Field: field, Desc: desc})
+	}
+
+	return orders, errs
+}
+
+func (f filterParam) parse(raw string) (Filter, error) {
+	filter := Filter{Field: f.field, Op: f.op}
+
+	switch f.kind {
+	case Number:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return Filter{}, fmt.Errorf("%s must be a number", f.param)
+		}
+		filter.Value = n
+	case Time:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", raw)
+			if err != nil {
+				return Filter{}, fmt.Errorf("%s must be a date or an RFC 3339 time", f.param)
+			}
+			if f.op == Lte {
+				t, filter.Op = t.AddDate(0, 0, 1), Lt
+			}
+		}
+		filter.Value = t
+	default:
+		filter.Value = raw
+	}
+
+	return filter, nil
+}
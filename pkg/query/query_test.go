@@ -0,0 +1,92 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func productBuilder() *Builder {
+	return NewBuilder().
+		Filter("name", "name", Contains, String).
+		Filter("price_min", "price", Gte, Number).
+		Filter("created_to", "created_at", Lte, Time).
+		SortBy("name", "name").
+		SortBy("price", "price").
+		SortBy("created_at", "created_at").
+		DefaultSort("-created_at")
+}
+
+func TestParseDefaults(t *testing.T) {
+	spec, err := productBuilder().Parse(url.Values{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, spec.Page)
+	assert.Equal(t, 10, spec.Limit)
+	assert.Empty(t, spec.Filters)
+	assert.Equal(t, []Order{{Field: "created_at", Desc: true}}, spec.Sort)
+}
+
+func TestParseOptionalPaging(t *testing.T) {
+	builder := productBuilder().OptionalPaging()
+
+	spec, err := builder.Parse(url.Values{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, spec.Limit)
+	assert.Equal(t, 0, spec.Offset())
+
+	spec, err = builder.Parse(url.Values{"page": {"2"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, spec.Limit)
+	assert.Equal(t, 10, spec.Offset())
+}
+
+func TestParse(t *testing.T) {
+	values, _ := url.ParseQuery("page=3&limit=20&name=chair&price_min=9.5&created_to=2024-05-01T10:00:00Z&sort=price,-name")
+
+	spec, err := productBuilder().Parse(values)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, spec.Page)
+	assert.Equal(t, 20, spec.Limit)
+	assert.Equal(t, 40, spec.Offset())
+	assert.Equal(t, []Filter{
+		{Field: "name", Op: Contains, Value: "chair"},
+		{Field: "price", Op: Gte, Value: 9.5},
+		{Field: "created_at", Op: Lte, Value: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+	}, spec.Filters)
+	assert.Equal(t, []Order{{Field: "price"}, {Field: "name", Desc: true}}, spec.Sort)
+}
+
+func TestParseDateUpperBoundCoversTheDay(t *testing.T) {
+	spec, err := productBuilder().Parse(url.Values{"created_to": {"2024-05-01"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []Filter{
+		{Field: "created_at", Op: Lt, Value: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+	}, spec.Filters)
+}
+
+func TestParseReportsEveryInvalidParameter(t *testing.T) {
+	values, _ := url.ParseQuery("page=0&limit=500&price_min=cheap&created_to=yesterday&sort=price,-owner")
+
+	_, err := productBuilder().Parse(values)
+	var errs Errors
+	assert.ErrorAs(t, err, &errs)
+
+	params := make([]string, len(errs))
+	for i, e := range errs {
+		params[i] = e.Param
+	}
+	assert.Equal(t, []string{"page", "limit", "price_min", "created_to", "sort"}, params)
+}
+
+func TestDefaultSortPanicsOnUnknownField(t *testing.T) {
+	assert.Panics(t, func() { NewBuilder().DefaultSort("price") })
+}
+
+func TestTotalPages(t *testing.T) {
+	assert.Equal(t, 1, Spec{Limit: 10}.TotalPages(0))
+	assert.Equal(t, 1, Spec{Limit: 10}.TotalPages(10))
+	assert.Equal(t, 3, Spec{Limit: 5}.TotalPages(13))
+	assert.Equal(t, 1, Spec{}.TotalPages(13))
+}
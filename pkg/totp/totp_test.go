@@ -0,0 +1,56 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the SHA1 vectors of RFC 6238 appendix B, truncated to six digits
+func TestCodeMatchesRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		code, err := Code(secret, Step(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, "time %d", unix)
+	}
+}
+
+func TestValidateAcceptsSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	previous, _ := Code(secret, Step(now)-1)
+	step, ok := Validate(secret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	stale, _ := Code(secret, Step(now)-3)
+	_, ok = Validate(secret, stale, now)
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Go API", "caio@caio.com", "JBSWY3DPEHPK3PXP"))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Go API:caio@caio.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "Go API", uri.Query().Get("issuer"))
+}
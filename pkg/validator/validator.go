@@ -0,0 +1,171 @@
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes one rule a field failed. Field is the JSON name of the field.
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// Errors collects every violation found in a single Validate call.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks the `validate` struct tags of v, which must be a struct or a pointer to one.
+// Rules are comma separated:
+//
+//	required  the field is not its zero value
+//	email     the string is a single bare address
+//	min=N     strings have at least N characters, numbers are at least N
+//	max=N     strings have at most N characters, numbers are at most N
+//	gt=N      numbers are strictly greater than N
+//	oneof=a b the string is one of the space separated values
+//
+// Pointer fields are optional: a nil pointer only fails `required`, and a non-nil one is checked
+// through to the value it points at. This suits partial updates where absent means unchanged.
+//
+// It returns nil or an Errors value holding every violation, in field order.
+func Validate(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validator: expected a struct, got %s", rv.Kind())
+	}
+
+	var errs Errors
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		name := jsonName(field)
+		value := rv.Field(i)
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				if hasRule(tag, "required") {
+					errs = append(errs, FieldError{Field: name, Rule: "required", Message: name + " is required"})
+				}
+				continue
+			}
+			value = value.Elem()
+		}
+
+		for _, rule := range strings.Split(tag, ",") {
+			rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			if message, ok := check(value, rule, param); !ok {
+				errs = append(errs, FieldError{Field: name, Rule: rule, Message: name + " " + message})
+				if rule == "required" {
+					break
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func check(value reflect.Value, rule, param string) (string, bool) {
+	switch rule {
+	case "required":
+		return "is required", !value.IsZero()
+	case "email":
+		if value.Kind() != reflect.String || value.String() == "" {
+			return "", true
+		}
+		addr, err := mail.ParseAddress(value.String())
+		return "must be a valid email address", err == nil && addr.Address == value.String()
+	case "oneof":
+		if value.Kind() != reflect.String || value.String() == "" {
+			return "", true
+		}
+		for _, allowed := range strings.Fields(param) {
+			if value.String() == allowed {
+				return "", true
+			}
+		}
+		return "must be one of: " + strings.Join(strings.Fields(param), ", "), false
+	case "min", "max", "gt":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validator: invalid parameter %q for rule %s", param, rule))
+		}
+		return compare(value, rule, limit)
+	}
+
+	panic(fmt.Sprintf("validator: unknown rule %q", rule))
+}
+
+func compare(value reflect.Value, rule string, limit float64) (string, bool) {
+	if value.Kind() == reflect.String {
+		length := float64(utf8.RuneCountInString(value.String()))
+		switch rule {
+		case "min":
+			return fmt.Sprintf("must be at least %g characters", limit), length >= limit
+		case "max":
+			return fmt.Sprintf("must be at most %g characters", limit), length <= limit
+		}
+		return fmt.Sprintf("must be longer than %g characters", limit), length > limit
+	}
+
+	var n float64
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	default:
+		panic(fmt.Sprintf("validator: rule %s does not apply to %s", rule, value.Kind()))
+	}
+
+	switch rule {
+	case "min":
+		return fmt.Sprintf("must be at least %g", limit), n >= limit
+	case "max":
+		return fmt.Sprintf("must be at most %g", limit), n <= limit
+	}
+	return fmt.Sprintf("must be greater than %g", limit), n > limit
+}
+
+func hasRule(tag, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(r), "="); name == rule {
+			return true
+		}
+	}
+
+	return false
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
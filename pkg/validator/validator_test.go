@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signup struct {
+	Name     string  `json:"name" validate:"required,max=5"`
+	Email    string  `json:"email" validate:"required,email"`
+	Password string  `json:"password" validate:"required,min=6"`
+	Age      int     `json:"age" validate:"min=18"`
+	Balance  float64 `json:"balance" validate:"gt=0"`
+	Note     string  `json:"note"`
+}
+
+func TestValidatePasses(t *testing.T) {
+	err := Validate(&signup{Name: "Caio", Email: "caio@caio.com", Password: "123456", Age: 30, Balance: 1})
+	assert.NoError(t, err)
+}
+
+func TestValidateCollectsEveryViolation(t *testing.T) {
+	err := Validate(signup{Name: "Caio Carvalho", Email: "not-an-email", Password: "1", Age: 10})
+
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Equal(t, Errors{
+		{Field: "name", Rule: "max", Message: "name must be at most 5 characters"},
+		{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		{Field: "password", Rule: "min", Message: "password must be at least 6 characters"},
+		{Field: "age", Rule: "min", Message: "age must be at least 18"},
+		{Field: "balance", Rule: "gt", Message: "balance must be greater than 0"},
+	}, errs)
+}
+
+func TestValidateRequiredStopsOtherRules(t *testing.T) {
+	err := Validate(&signup{Age: 18, Balance: 1})
+
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 3)
+	for _, fe := range errs {
+		assert.Equal(t, "required", fe.Rule)
+	}
+}
+
+func TestValidateRejectsDisplayNameEmails(t *testing.T) {
+	err := Validate(&struct {
+		Email string `json:"email" validate:"email"`
+	}{Email: "Caio <caio@caio.com>"})
+	assert.Error(t, err)
+}
+
+func TestValidateOneOf(t *testing.T) {
+	type input struct {
+		Role string `json:"role" validate:"oneof=admin editor"`
+	}
+
+	assert.NoError(t, Validate(&input{Role: "editor"}))
+
+	err := Validate(&input{Role: "root"})
+	assert.Equal(t, Errors{{Field: "role", Rule: "oneof", Message: "role must be one of: admin, editor"}}, err)
+}
+
+func TestValidatePointerFields(t *testing.T) {
+	type patch struct {
+		Name  *string `json:"name" validate:"min=1,max=5"`
+		Email *string `json:"email" validate:"required,email"`
+	}
+
+	email := "caio@caio.com"
+	assert.NoError(t, Validate(&patch{Email: &email}))
+
+	empty, long := "", "Caio Carvalho"
+	err := Validate(&patch{Name: &empty})
+	assert.Equal(t, Errors{
+		{Field: "name", Rule: "min", Message: "name must be at least 1 characters"},
+		{Field: "email", Rule: "required", Message: "email is required"},
+	}, err)
+
+	err = Validate(&patch{Name: &long, Email: &email})
+	assert.Equal(t, Errors{{Field: "name", Rule: "max", Message: "name must be at most 5 characters"}}, err)
+}